@@ -16,19 +16,25 @@ package app
 
 import (
 	"context"
+	"crypto/sha1"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/pkg/browser"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 
 	"groundcontrol/appcontext"
 	"groundcontrol/config"
+	"groundcontrol/gitutil"
 	"groundcontrol/job"
 	"groundcontrol/log"
 	"groundcontrol/model"
@@ -37,6 +43,7 @@ import (
 	"groundcontrol/service"
 	"groundcontrol/store"
 	"groundcontrol/util"
+	"groundcontrol/watch"
 	"groundcontrol/work"
 
 	_ "net/http/pprof"
@@ -47,9 +54,12 @@ type App struct {
 	// All of these can be set by passing options to New().
 	sourcesFile                   string
 	keysFile                      string
+	overridesFile                 string
 	listenAddress                 string
 	jobsConcurrency               int
 	jobsChannelSize               int
+	jobTimeouts                   map[string]time.Duration
+	gitCredentials                []gitutil.Credentials
 	logLevel                      model.LogLevel
 	logCap                        int
 	pubSubHistoryCap              int
@@ -66,6 +76,8 @@ type App struct {
 	enableApolloTracing           bool
 	enableSignalHandling          bool
 	pprofListenAddress            string
+	enableWatcher                 bool
+	watcherMaxDirectories         int
 	newRunner                     appcontext.NewRunner
 	// The app needs to launch a few Goroutines, and a wait group is used to
 	// make sure they finish before exiting.
@@ -81,6 +93,7 @@ func New(opts ...Opt) *App {
 		listenAddress:                 DefaultListenAddress,
 		jobsConcurrency:               DefaultJobsConcurrency,
 		jobsChannelSize:               DefaultJobsChannelSize,
+		jobTimeouts:                   job.DefaultTimeouts,
 		logLevel:                      DefaultLogLevel,
 		logCap:                        DefaultLogCap,
 		pubSubHistoryCap:              DefaultPubSubHistoryCap,
@@ -97,10 +110,15 @@ func New(opts ...Opt) *App {
 		enableApolloTracing:           DefaultEnableApolloTracing,
 		enableSignalHandling:          DefaultEnableSignalHandling,
 		pprofListenAddress:            DefaultPprofListenAddress,
+		enableWatcher:                 DefaultEnableWatcher,
+		watcherMaxDirectories:         DefaultWatcherMaxDirectories,
 	}
 	for _, opt := range opts {
 		opt(app)
 	}
+	if app.overridesFile == "" {
+		app.overridesFile = filepath.Join(filepath.Dir(app.sourcesFile), DefaultOverridesFile)
+	}
 	return app
 }
 
@@ -117,6 +135,10 @@ func (a *App) Start(ctx context.Context) error {
 	defer cancel()
 	a.createBaseNodes(ctx) // sets appCtx.systemID
 	appCtx.Log.InfoWithOwner(ctx, appCtx.SystemID, "starting app")
+	a.removeProjectCaches(ctx)
+	if err := a.createOverrides(ctx); err != nil {
+		return err
+	}
 	if err := a.createSources(ctx); err != nil {
 		return err
 	}
@@ -132,6 +154,9 @@ func (a *App) Start(ctx context.Context) error {
 	a.serve(ctx, server, cancel)
 	a.startJobs(ctx, cancel)
 	a.startPeriodicJobs(ctx, cancel)
+	if a.enableWatcher {
+		a.startWatcher(ctx, cancel)
+	}
 	if a.enableSignalHandling {
 		a.handleSignals(ctx, server, cancel)
 	}
@@ -156,13 +181,17 @@ func (a *App) createAppContext() *appcontext.Context {
 	return &appcontext.Context{
 		Nodes:                         store.NewMemory(),
 		Log:                           log.NewLogger(a.logCap, a.logLevel),
-		Jobs:                          work.NewQueue(a.jobsConcurrency, a.jobsChannelSize),
+		Jobs:                          work.NewQueue(a.jobsConcurrency, a.jobsChannelSize, a.jobTimeouts),
 		Services:                      service.NewManager(),
 		Subs:                          pubsub.New(a.pubSubHistoryCap),
 		SubChannelSize:                a.subscriptionChannelSize,
 		GetGitSourcePath:              a.getGitSourcePath,
+		GetHTTPSourcePath:             a.getHTTPSourcePath,
 		GetProjectPath:                a.getProjectPath,
-		GetProjectCachePath:           a.getProjectCachePath,
+		GetRepositoryCachePath:        a.getRepositoryCachePath,
+		GetTaskRunsPath:               a.getTaskRunsPath,
+		GetSnapshotsPath:              a.getSnapshotsPath,
+		GetGitAuth:                    a.getGitAuth,
 		NewRunner:                     a.newRunner,
 		RunnerGracefulShutdownTimeout: a.runnerGracefulShutdownTimeout,
 		OpenEditorCommand:             a.openEditorCommand,
@@ -198,6 +227,40 @@ func (a *App) createBaseNodes(ctx context.Context) {
 	appCtx.SystemID = systemID
 }
 
+// removeProjectCaches removes the caches that were kept per project before
+// projects tracking the same repository shared a cache.
+func (a *App) removeProjectCaches(ctx context.Context) {
+	appCtx := appcontext.Get(ctx)
+	log := appCtx.Log
+	systemID := appCtx.SystemID
+	repositories := filepath.Join(a.cacheDirectory, "repositories")
+	matches, err := filepath.Glob(filepath.Join(a.cacheDirectory, "*", "*.git"))
+	if err != nil {
+		log.WarningWithOwner(ctx, systemID, "could not find old project caches because %s", err.Error())
+		return
+	}
+	for _, match := range matches {
+		if filepath.Dir(match) == repositories || !util.FileExists(filepath.Join(match, "objects")) {
+			continue
+		}
+		if err := os.RemoveAll(match); err != nil {
+			log.WarningWithOwner(ctx, systemID, "could not remove old project cache %s because %s", match, err.Error())
+			continue
+		}
+		log.InfoWithOwner(ctx, systemID, "removed old project cache %s", match)
+	}
+}
+
+// createOverrides loads the overrides config file.
+func (a *App) createOverrides(ctx context.Context) error {
+	cfg, err := config.LoadOverridesYAML(a.overridesFile)
+	if err != nil {
+		return err
+	}
+	appcontext.Get(ctx).Overrides = cfg
+	return nil
+}
+
 // createSources loads the sources config file and creates the Relay nodes for
 // them.
 func (a *App) createSources(ctx context.Context) error {
@@ -272,6 +335,13 @@ func (a *App) startPeriodicJobs(ctx context.Context, cancel func()) {
 	})
 }
 
+// startWatcher starts watching the files of sources and projects in a
+// Goroutine so that they are synced as soon as they change.
+func (a *App) startWatcher(ctx context.Context, cancel func()) {
+	watcher := watch.New(a.watcherMaxDirectories, watch.DefaultDebounce)
+	a.proc(ctx, "file watcher", cancel, watcher.Watch)
+}
+
 // handleSignals starts a Goroutine that will cancel the app context once
 // a SIGTERM or SIGINT signal is received.
 func (a *App) handleSignals(ctx context.Context, server *http.Server, cancel func()) {
@@ -378,17 +448,59 @@ func (a *App) getGitSourcePath(repo, reference string) string {
 	return filepath.Join(a.gitSourcesDirectory, name, reference)
 }
 
+// getHTTPSourcePath returns the path to the file where the content of an HTTP
+// source URL is cached.
+func (a *App) getHTTPSourcePath(u string) string {
+	name := path.Base(u)
+	ext := path.Ext(name)
+	name = name[:len(name)-len(ext)]
+	sum := sha1.Sum([]byte(u))
+	return filepath.Join(a.cacheDirectory, "http-sources", fmt.Sprintf("%s-%x.yml", name, sum[:4]))
+}
+
 // getProjectPath returns the path to the directory where the files of a
 // project are stored.
 func (a *App) getProjectPath(workspaceSlug, projectSlug string) string {
 	return filepath.Join(a.workspacesDirectory, workspaceSlug, projectSlug)
 }
 
-// getProjectCachePath returns the path to the directory where the files of a
-// project cache are stored. The cache of a project is just a bare clone of the
-// repository.
-func (a *App) getProjectCachePath(workspaceSlug, projectSlug string) string {
-	return filepath.Join(a.cacheDirectory, workspaceSlug, projectSlug+".git")
+// getRepositoryCachePath returns the path to the directory where the cache of
+// a repository is stored. The cache is a bare clone of the repository shared
+// by all the projects tracking it. A hash of the URL keeps the path unique.
+func (a *App) getRepositoryCachePath(repo string) string {
+	name := path.Base(repo)
+	ext := path.Ext(name)
+	name = name[:len(name)-len(ext)]
+	sum := sha1.Sum([]byte(repo))
+	return filepath.Join(a.cacheDirectory, "repositories", fmt.Sprintf("%s-%x.git", name, sum[:4]))
+}
+
+// getTaskRunsPath returns the path to the file where the runs of a task are
+// stored. The name of the task is escaped since it can contain any character.
+func (a *App) getTaskRunsPath(workspaceSlug, taskName string) string {
+	return filepath.Join(a.cacheDirectory, workspaceSlug, "runs", url.PathEscape(taskName)+".json")
+}
+
+// getSnapshotsPath returns the path to the directory where the snapshots of a
+// workspace are stored.
+func (a *App) getSnapshotsPath(workspaceSlug string) string {
+	return filepath.Join(a.cacheDirectory, workspaceSlug, "snapshots")
+}
+
+// getGitAuth returns the authentication method for a Git repository using
+// the credentials of its host. It returns nil if the host doesn't have any,
+// in which case go-git uses the SSH agent for SSH repositories.
+func (a *App) getGitAuth(ctx context.Context, repo string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, credentials := range a.gitCredentials {
+		if strings.EqualFold(credentials.Host, endpoint.Host) {
+			return credentials.AuthMethod(ctx, endpoint, appcontext.Get(ctx).Keys.All())
+		}
+	}
+	return nil, nil
 }
 
 // proc is used to launch a long-running Goroutine, and takes care of updating
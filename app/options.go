@@ -18,11 +18,13 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 
 	"groundcontrol/appcontext"
+	"groundcontrol/gitutil"
 	"groundcontrol/model"
 	"groundcontrol/shell"
 )
@@ -58,6 +60,10 @@ const (
 	DefaultOpenEditorCommand = "code --goto %s"
 	// DefaultPprofListenAddress is the default pprof listen address.
 	DefaultPprofListenAddress = ""
+	// DefaultEnableWatcher is whether to watch the files of sources and projects by default.
+	DefaultEnableWatcher = true
+	// DefaultWatcherMaxDirectories is the default maximum number of directories the watcher can watch.
+	DefaultWatcherMaxDirectories = 4096
 )
 
 var (
@@ -67,6 +73,9 @@ var (
 	DefaultSourcesFile = "sources.yml"
 	// DefaultKeysFile is the default keys file.
 	DefaultKeysFile = "keys.yml"
+	// DefaultOverridesFile is the default name of the overrides file, which is
+	// in the directory of the sources file.
+	DefaultOverridesFile = "overrides.yml"
 	// DefaultGitSourcesDirectory is the default Git sources directory.
 	DefaultGitSourcesDirectory = "git-sources"
 	// DefaultWorkspacesDirectory is the default workspace directory.
@@ -108,6 +117,14 @@ func OptKeysFile(filename string) Opt {
 	}
 }
 
+// OptOverridesFile sets the overrides file.
+// If it is empty, the overrides file is next to the sources file.
+func OptOverridesFile(filename string) Opt {
+	return func(app *App) {
+		app.overridesFile = filename
+	}
+}
+
 // OptListenAddress sets the listen address.
 func OptListenAddress(address string) Opt {
 	return func(app *App) {
@@ -129,6 +146,36 @@ func OptJobsChannelSize(size int) Opt {
 	}
 }
 
+// OptJobTimeouts overrides the timeouts of jobs.
+// Names are matched case insensitively, and dashes can be used instead of spaces.
+// A zero duration disables the timeout.
+func OptJobTimeouts(timeouts map[string]time.Duration) Opt {
+	return func(app *App) {
+		merged := map[string]time.Duration{}
+		for name, timeout := range app.jobTimeouts {
+			merged[name] = timeout
+		}
+		for key, timeout := range timeouts {
+			name := key
+			for jobName := range merged {
+				if strings.EqualFold(strings.Replace(key, "-", " ", -1), jobName) {
+					name = jobName
+					break
+				}
+			}
+			merged[name] = timeout
+		}
+		app.jobTimeouts = merged
+	}
+}
+
+// OptGitCredentials sets the credentials used to authenticate to Git hosts.
+func OptGitCredentials(credentials []gitutil.Credentials) Opt {
+	return func(app *App) {
+		app.gitCredentials = credentials
+	}
+}
+
 // OptLogLevel sets the minimum level for log messages.
 func OptLogLevel(level model.LogLevel) Opt {
 	return func(app *App) {
@@ -247,3 +294,17 @@ func OptPprofListenAddress(address string) Opt {
 		app.pprofListenAddress = address
 	}
 }
+
+// OptEnableWatcher tells the app whether to watch the files of sources and projects.
+func OptEnableWatcher(enable bool) Opt {
+	return func(app *App) {
+		app.enableWatcher = enable
+	}
+}
+
+// OptWatcherMaxDirectories sets the maximum number of directories the watcher can watch.
+func OptWatcherMaxDirectories(max int) Opt {
+	return func(app *App) {
+		app.watcherMaxDirectories = max
+	}
+}
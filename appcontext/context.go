@@ -19,12 +19,18 @@ import (
 	"io"
 	"time"
 
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"groundcontrol/retry"
 	"groundcontrol/store"
 )
 
 type key string
 
-const contextKey key = "groundcontrol_app_context"
+const (
+	contextKey key = "groundcontrol_app_context"
+	jobIDKey   key = "groundcontrol_job_id"
+)
 
 // Context contains variables that are passed to most app functions.
 type Context struct {
@@ -35,9 +41,14 @@ type Context struct {
 	Subs                          Subs
 	Sources                       Sources
 	Keys                          Keys
+	Overrides                     Overrides
 	GetGitSourcePath              ProjectGitSourcePathGetter
+	GetHTTPSourcePath             HTTPSourcePathGetter
 	GetProjectPath                ProjectPathGetter
-	GetProjectCachePath           ProjectCachePathGetter
+	GetRepositoryCachePath        RepositoryCachePathGetter
+	GetTaskRunsPath               TaskRunsPathGetter
+	GetSnapshotsPath              SnapshotsPathGetter
+	GetGitAuth                    GitAuthGetter
 	NewRunner                     NewRunner
 	RunnerGracefulShutdownTimeout time.Duration
 	OpenEditorCommand             string
@@ -59,6 +70,20 @@ func Get(ctx context.Context) *Context {
 	return nil
 }
 
+// WithJobID adds the ID of the running Job to a Go context.
+func WithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey, jobID)
+}
+
+// JobID retrieves the ID of the running Job from a Go context.
+// It returns an empty string if the context doesn't belong to a Job.
+func JobID(ctx context.Context) string {
+	if val, ok := ctx.Value(jobIDKey).(string); ok {
+		return val
+	}
+	return ""
+}
+
 // Nodes exposes low-level functions to load, store, and lock Nodes.
 type Nodes interface {
 	// Store stores a node.
@@ -100,7 +125,8 @@ type Jobs interface {
 	// Work starts running jobs and blocks until the context is done.
 	Work(ctx context.Context) error
 	// Add adds a job to the queue and returns the job's ID.
-	Add(ctx context.Context, name string, ownerID string, highPriority bool, fn func(ctx context.Context) error) string
+	// The retry policy can be nil if the job shouldn't be retried.
+	Add(ctx context.Context, name string, ownerID string, highPriority bool, policy *retry.Policy, fn func(ctx context.Context) error) string
 	// Stop cancels a QUEUED or RUNNING job.
 	Stop(ctx context.Context, id string) error
 }
@@ -136,6 +162,11 @@ type Sources interface {
 	// SetGitSource sets a Git source and stores the corresponding node.
 	// It returns the ID of the source.
 	SetGitSource(ctx context.Context, repository, reference string) string
+	// SetHTTPSource sets an HTTP source and stores the corresponding node.
+	// It returns the ID of the source.
+	SetHTTPSource(ctx context.Context, urls []string) string
+	// ApproveGitSource sets the approved commit of a Git source.
+	ApproveGitSource(ctx context.Context, id, commit string)
 	// Delete deletes a source.
 	Delete(ctx context.Context, id string) error
 	// Save saves the config to disk, overwriting the file if it exists.
@@ -154,6 +185,28 @@ type Keys interface {
 	Save() error
 }
 
+// Overrides exposes functions to load and store local overrides of workspaces to disk.
+type Overrides interface {
+	// ProjectReference returns the overridden reference of a project.
+	ProjectReference(workspaceSlug, projectSlug string) (string, bool)
+	// VariableDefault returns the overridden default value of the variables
+	// with the given name in a workspace.
+	VariableDefault(workspaceSlug, name string) (string, bool)
+	// ServiceCommand returns the overridden command of a service.
+	ServiceCommand(workspaceSlug, serviceName string) (string, bool)
+	// SetProjectReference overrides the reference of a project.
+	// A nil reference removes the override.
+	SetProjectReference(workspaceSlug, projectSlug string, reference *string)
+	// SetVariableDefault overrides the default value of the variables with the
+	// given name in a workspace. A nil value removes the override.
+	SetVariableDefault(workspaceSlug, name string, value *string)
+	// SetServiceCommand overrides the command of a service.
+	// A nil command removes the override.
+	SetServiceCommand(workspaceSlug, serviceName string, command *string)
+	// Save saves the overrides to disk, overwriting the file if it exists.
+	Save() error
+}
+
 // Runner executes shell commands.
 type Runner interface {
 	// Run can be called multiple times to execute shell commands.
@@ -163,11 +216,24 @@ type Runner interface {
 // ProjectGitSourcePathGetter is a function that returns the path to a Git source.
 type ProjectGitSourcePathGetter func(repo, reference string) string
 
+// HTTPSourcePathGetter is a function that returns the path to the cached file of an HTTP source URL.
+type HTTPSourcePathGetter func(url string) string
+
 // ProjectPathGetter is a function that returns the path to a project.
 type ProjectPathGetter func(workspaceSlug, projectSlug string) string
 
-// ProjectCachePathGetter is a function that returns the path to a project's cache.
-type ProjectCachePathGetter func(workspaceSlug, projectSlug string) string
+// RepositoryCachePathGetter is a function that returns the path to the cache of a repository.
+type RepositoryCachePathGetter func(repo string) string
+
+// TaskRunsPathGetter is a function that returns the path to the file storing the runs of a task.
+type TaskRunsPathGetter func(workspaceSlug, taskName string) string
+
+// SnapshotsPathGetter is a function that returns the path to the directory storing the snapshots of a workspace.
+type SnapshotsPathGetter func(workspaceSlug string) string
+
+// GitAuthGetter is a function that returns the authentication method for a Git repository.
+// It returns nil if there are no credentials for the repository.
+type GitAuthGetter func(ctx context.Context, repo string) (transport.AuthMethod, error)
 
 // NewRunner is a function that returns a runner.
 type NewRunner func(stdout, stderr io.Writer, dir string, env []string, gracefulShutdownTimeout time.Duration) (Runner, error)
@@ -17,15 +17,18 @@ package cmd
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"groundcontrol/app"
+	"groundcontrol/gitutil"
 	"groundcontrol/model"
 )
 
@@ -48,9 +51,12 @@ Complete documentation is available at https://github.com/stratumn/groundcontrol
 		app := app.New(
 			app.OptSourcesFile(viper.GetString("sources-file")),
 			app.OptKeysFile(viper.GetString("keys-file")),
+			app.OptOverridesFile(viper.GetString("overrides-file")),
 			app.OptListenAddress(viper.GetString("listen-address")),
 			app.OptJobsConcurrency(viper.GetInt("jobs-concurrency")),
 			app.OptJobsChannelSize(viper.GetInt("jobs-channel-size")),
+			app.OptJobTimeouts(jobTimeouts()),
+			app.OptGitCredentials(gitCredentials()),
 			app.OptLogLevel(model.LogLevel(strings.ToUpper(viper.GetString("log-level")))),
 			app.OptLogCap(viper.GetInt("log-cap")),
 			app.OptPubSubHistoryCap(viper.GetInt("pubsub-history-cap")),
@@ -65,6 +71,8 @@ Complete documentation is available at https://github.com/stratumn/groundcontrol
 			app.OptOpenEditorCommand(viper.GetString("open-editor-command")),
 			app.OptEnableApolloTracing(viper.GetBool("enable-apollo-tracing")),
 			app.OptPprofListenAddress(viper.GetString("pprof-listen-address")),
+			app.OptEnableWatcher(viper.GetBool("enable-watcher")),
+			app.OptWatcherMaxDirectories(viper.GetInt("watcher-max-directories")),
 			app.OptUI(userInterface),
 		)
 		return app.Start(context.Background())
@@ -88,6 +96,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&settingsFile, "settings-file", app.DefaultSettingsFile, "settings file")
 	rootCmd.PersistentFlags().String("sources-file", app.DefaultSourcesFile, "sources config file")
 	rootCmd.PersistentFlags().String("keys-file", app.DefaultKeysFile, "keys config file")
+	rootCmd.PersistentFlags().String("overrides-file", "", "workspace overrides config file (default is "+app.DefaultOverridesFile+" in the directory of the sources file)")
 	rootCmd.PersistentFlags().String("listen-address", app.DefaultListenAddress, "address the server should listen on")
 	rootCmd.PersistentFlags().Int("jobs-concurrency", app.DefaultJobsConcurrency, "how many jobs can run concurrency")
 	rootCmd.PersistentFlags().Int("jobs-channel-size", app.DefaultJobsChannelSize, "how many jobs a work queue can hold")
@@ -105,9 +114,12 @@ func init() {
 	rootCmd.PersistentFlags().String("open-editor-command", app.DefaultOpenEditorCommand, "command issued to open a text editor")
 	rootCmd.PersistentFlags().Bool("enable-apollo-tracing", app.DefaultEnableApolloTracing, "enable the Apollo tracing middleware")
 	rootCmd.PersistentFlags().String("pprof-listen-address", app.DefaultPprofListenAddress, "address the profiler should listen on")
+	rootCmd.PersistentFlags().Bool("enable-watcher", app.DefaultEnableWatcher, "sync sources and projects as soon as their files change")
+	rootCmd.PersistentFlags().Int("watcher-max-directories", app.DefaultWatcherMaxDirectories, "maximum number of directories the file watcher can watch")
 	for _, flagName := range []string{
 		"sources-file",
 		"keys-file",
+		"overrides-file",
 		"listen-address",
 		"jobs-concurrency",
 		"jobs-channel-size",
@@ -125,6 +137,8 @@ func init() {
 		"open-editor-command",
 		"enable-apollo-tracing",
 		"pprof-listen-address",
+		"enable-watcher",
+		"watcher-max-directories",
 	} {
 		if err := viper.BindPFlag(flagName, rootCmd.PersistentFlags().Lookup(flagName)); err != nil {
 			panic(err)
@@ -132,6 +146,31 @@ func init() {
 	}
 }
 
+// jobTimeouts returns the job timeouts set in the settings file.
+// Invalid durations are ignored.
+func jobTimeouts() map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+	for name, value := range viper.GetStringMapString("job-timeouts") {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("WARNING\tinvalid timeout for job %s because %s", name, err.Error())
+			continue
+		}
+		timeouts[name] = timeout
+	}
+	return timeouts
+}
+
+// gitCredentials returns the credentials of Git hosts set in the settings file.
+func gitCredentials() []gitutil.Credentials {
+	var credentials []gitutil.Credentials
+	if err := viper.UnmarshalKey("git-credentials", &credentials); err != nil {
+		log.Printf("WARNING\tinvalid Git credentials because %s", err.Error())
+		return nil
+	}
+	return credentials
+}
+
 // initSettings reads in settings file and ENV variables if set.
 func initSettings() {
 	if settingsFile != "" {
@@ -0,0 +1,193 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Overrides stores local changes to shared workspaces in a YAML file.
+type Overrides struct {
+	mu         sync.RWMutex
+	Filename   string                         `json:"-" yaml:"-"`
+	Workspaces map[string]*WorkspaceOverrides `json:"workspaces" yaml:"workspaces"`
+}
+
+// WorkspaceOverrides contains the overrides of a workspace.
+type WorkspaceOverrides struct {
+	Projects  map[string]*ProjectOverrides `json:"projects" yaml:"projects,omitempty"`
+	Variables map[string]string            `json:"variables" yaml:"variables,omitempty"`
+	Services  map[string]*ServiceOverrides `json:"services" yaml:"services,omitempty"`
+}
+
+// ProjectOverrides contains the overrides of a project.
+type ProjectOverrides struct {
+	Reference string `json:"reference" yaml:"reference,omitempty"`
+}
+
+// ServiceOverrides contains the overrides of a service.
+type ServiceOverrides struct {
+	Command string `json:"command" yaml:"command,omitempty"`
+}
+
+// ProjectReference returns the overridden reference of a project.
+func (c *Overrides) ProjectReference(workspaceSlug, projectSlug string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	workspace := c.Workspaces[workspaceSlug]
+	if workspace == nil {
+		return "", false
+	}
+	project := workspace.Projects[projectSlug]
+	if project == nil || project.Reference == "" {
+		return "", false
+	}
+	return project.Reference, true
+}
+
+// VariableDefault returns the overridden default value of the variables
+// with the given name in a workspace.
+func (c *Overrides) VariableDefault(workspaceSlug, name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	workspace := c.Workspaces[workspaceSlug]
+	if workspace == nil {
+		return "", false
+	}
+	value, ok := workspace.Variables[name]
+	return value, ok
+}
+
+// ServiceCommand returns the overridden command of a service.
+func (c *Overrides) ServiceCommand(workspaceSlug, serviceName string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	workspace := c.Workspaces[workspaceSlug]
+	if workspace == nil {
+		return "", false
+	}
+	service := workspace.Services[serviceName]
+	if service == nil || service.Command == "" {
+		return "", false
+	}
+	return service.Command, true
+}
+
+// SetProjectReference overrides the reference of a project.
+// A nil reference removes the override.
+func (c *Overrides) SetProjectReference(workspaceSlug, projectSlug string, reference *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	workspace := c.workspace(workspaceSlug)
+	if reference == nil {
+		delete(workspace.Projects, projectSlug)
+	} else {
+		if workspace.Projects == nil {
+			workspace.Projects = map[string]*ProjectOverrides{}
+		}
+		workspace.Projects[projectSlug] = &ProjectOverrides{Reference: *reference}
+	}
+	c.prune(workspaceSlug)
+}
+
+// SetVariableDefault overrides the default value of the variables with the
+// given name in a workspace. A nil value removes the override.
+func (c *Overrides) SetVariableDefault(workspaceSlug, name string, value *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	workspace := c.workspace(workspaceSlug)
+	if value == nil {
+		delete(workspace.Variables, name)
+	} else {
+		if workspace.Variables == nil {
+			workspace.Variables = map[string]string{}
+		}
+		workspace.Variables[name] = *value
+	}
+	c.prune(workspaceSlug)
+}
+
+// SetServiceCommand overrides the command of a service.
+// A nil command removes the override.
+func (c *Overrides) SetServiceCommand(workspaceSlug, serviceName string, command *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	workspace := c.workspace(workspaceSlug)
+	if command == nil {
+		delete(workspace.Services, serviceName)
+	} else {
+		if workspace.Services == nil {
+			workspace.Services = map[string]*ServiceOverrides{}
+		}
+		workspace.Services[serviceName] = &ServiceOverrides{Command: *command}
+	}
+	c.prune(workspaceSlug)
+}
+
+// workspace returns the overrides of a workspace, creating them if needed.
+// The lock must be held.
+func (c *Overrides) workspace(slug string) *WorkspaceOverrides {
+	if c.Workspaces == nil {
+		c.Workspaces = map[string]*WorkspaceOverrides{}
+	}
+	workspace := c.Workspaces[slug]
+	if workspace == nil {
+		workspace = &WorkspaceOverrides{}
+		c.Workspaces[slug] = workspace
+	}
+	return workspace
+}
+
+// prune removes the overrides of a workspace if they are empty so that the
+// file stays tidy. The lock must be held.
+func (c *Overrides) prune(slug string) {
+	workspace := c.Workspaces[slug]
+	if len(workspace.Projects) < 1 && len(workspace.Variables) < 1 && len(workspace.Services) < 1 {
+		delete(c.Workspaces, slug)
+	}
+}
+
+// Save saves the overrides to disk, overwriting the file if it exists.
+func (c *Overrides) Save() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	bytes, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.Filename, bytes, 0644)
+}
+
+// LoadOverridesYAML loads overrides from a YAML file.
+// It will create a file if it doesn't exist.
+func LoadOverridesYAML(filename string) (*Overrides, error) {
+	config := Overrides{Filename: filename, Workspaces: map[string]*WorkspaceOverrides{}}
+	bytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return &config, config.Save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config, yaml.UnmarshalStrict(bytes, &config)
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrides(t *testing.T) {
+	reference := "refs/heads/feature"
+	value := "3001"
+	command := "make run-debug"
+
+	c := &Overrides{}
+	c.SetProjectReference("workspace", "project", &reference)
+	c.SetVariableDefault("workspace", "PORT", &value)
+	c.SetServiceCommand("workspace", "Service", &command)
+
+	got, ok := c.ProjectReference("workspace", "project")
+	assert.True(t, ok)
+	assert.Equal(t, reference, got)
+	got, ok = c.VariableDefault("workspace", "PORT")
+	assert.True(t, ok)
+	assert.Equal(t, value, got)
+	got, ok = c.ServiceCommand("workspace", "Service")
+	assert.True(t, ok)
+	assert.Equal(t, command, got)
+
+	_, ok = c.ProjectReference("workspace", "other")
+	assert.False(t, ok)
+	_, ok = c.VariableDefault("other", "PORT")
+	assert.False(t, ok)
+
+	c.SetProjectReference("workspace", "project", nil)
+	c.SetVariableDefault("workspace", "PORT", nil)
+	_, ok = c.ProjectReference("workspace", "project")
+	assert.False(t, ok)
+	assert.Contains(t, c.Workspaces, "workspace", "the service override is left")
+
+	c.SetServiceCommand("workspace", "Service", nil)
+	assert.Empty(t, c.Workspaces, "empty workspaces are pruned")
+}
+
+func TestOverrides_emptyVariable(t *testing.T) {
+	empty := ""
+	c := &Overrides{}
+	c.SetVariableDefault("workspace", "PORT", &empty)
+
+	got, ok := c.VariableDefault("workspace", "PORT")
+	assert.True(t, ok, "a variable can be overridden with an empty value")
+	assert.Equal(t, "", got)
+}
+
+func TestLoadOverridesYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overrides")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "groundcontrol", "overrides.yml")
+
+	c, err := LoadOverridesYAML(filename)
+	require.NoError(t, err)
+	assert.FileExists(t, filename, "the file is created")
+
+	reference := "refs/heads/feature"
+	c.SetProjectReference("workspace", "project", &reference)
+	require.NoError(t, c.Save())
+
+	c, err = LoadOverridesYAML(filename)
+	require.NoError(t, err)
+	got, ok := c.ProjectReference("workspace", "project")
+	assert.True(t, ok)
+	assert.Equal(t, reference, got)
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("unknown: true\n"), 0644))
+	_, err = LoadOverridesYAML(filename)
+	assert.Error(t, err, "unknown keys are rejected")
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// DefaultSSHUser is the SSH user when the URL of a repository doesn't have one.
+const DefaultSSHUser = "git"
+
+// DefaultTokenUsername is the HTTPS username used with a token when none is set.
+// Most Git hosts ignore the username when authenticating with a token.
+const DefaultTokenUsername = "git"
+
+// ErrNoCredentials is returned when the credential helper doesn't have credentials for a host.
+var ErrNoCredentials = errors.New("the credential helper didn't return credentials")
+
+// Credentials configure how to authenticate to a Git host.
+// Secrets are read from keys so that they aren't stored in the settings file.
+type Credentials struct {
+	// Host is the host the credentials are used for, for instance github.com.
+	Host string `mapstructure:"host"`
+	// SSHKey is the path to an SSH private key.
+	SSHKey string `mapstructure:"ssh-key"`
+	// SSHPassphraseKey is the name of the key containing the passphrase of the SSH private key.
+	SSHPassphraseKey string `mapstructure:"ssh-passphrase-key"`
+	// Username is the username used with the HTTPS token.
+	Username string `mapstructure:"username"`
+	// TokenKey is the name of the key containing the HTTPS token.
+	TokenKey string `mapstructure:"token-key"`
+	// CredentialHelper gets HTTPS credentials from git credential fill.
+	CredentialHelper bool `mapstructure:"credential-helper"`
+}
+
+// AuthMethod returns the authentication method for an endpoint.
+// It returns nil if there is none for the protocol of the endpoint, in
+// which case go-git falls back to the SSH agent for SSH endpoints.
+func (c *Credentials) AuthMethod(
+	ctx context.Context,
+	endpoint *transport.Endpoint,
+	keys map[string]string,
+) (transport.AuthMethod, error) {
+	switch endpoint.Protocol {
+	case "ssh":
+		if c.SSHKey == "" {
+			return nil, nil
+		}
+		path, err := homedir.Expand(c.SSHKey)
+		if err != nil {
+			return nil, err
+		}
+		user := endpoint.User
+		if user == "" {
+			user = DefaultSSHUser
+		}
+		return ssh.NewPublicKeysFromFile(user, path, keys[c.SSHPassphraseKey])
+	case "http", "https":
+		if c.TokenKey != "" {
+			username := c.Username
+			if username == "" {
+				username = DefaultTokenUsername
+			}
+			return &http.BasicAuth{Username: username, Password: keys[c.TokenKey]}, nil
+		}
+		if c.CredentialHelper {
+			return CredentialFill(ctx, endpoint)
+		}
+	}
+	return nil, nil
+}
+
+// CredentialFill gets HTTPS credentials for an endpoint from git credential fill.
+// Git is not allowed to prompt for credentials.
+func CredentialFill(ctx context.Context, endpoint *transport.Endpoint) (*http.BasicAuth, error) {
+	input := fmt.Sprintf(
+		"protocol=%s\nhost=%s\npath=%s\n\n",
+		endpoint.Protocol,
+		endpoint.Host,
+		strings.TrimPrefix(endpoint.Path, "/"),
+	)
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+	auth := &http.BasicAuth{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		switch parts[0] {
+		case "username":
+			auth.Username = parts[1]
+		case "password":
+			auth.Password = parts[1]
+		}
+	}
+	if auth.Password == "" {
+		return nil, ErrNoCredentials
+	}
+	return auth, scanner.Err()
+}
+
+// IsAuthError returns whether an error returned by go-git is caused by
+// failed authentication rather than, for instance, a network failure.
+func IsAuthError(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case transport.ErrAuthenticationRequired,
+		transport.ErrAuthorizationFailed,
+		transport.ErrInvalidAuthMethod,
+		ErrNoCredentials:
+		return true
+	}
+	return strings.Contains(err.Error(), "ssh: unable to authenticate")
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// Hunk is a contiguous block of changes in a unified diff.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Header   string
+	Lines    []string
+}
+
+// ParseDiff parses the hunks of a unified diff.
+// File headers are ignored.
+func ParseDiff(out []byte) ([]Hunk, error) {
+	var (
+		hunks []Hunk
+		hunk  *Hunk
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := hunkHeaderRegexp.FindStringSubmatch(line); matches != nil {
+			hunks = append(hunks, Hunk{
+				OldStart: atoi(matches[1], 0),
+				OldLines: atoi(matches[2], 1),
+				NewStart: atoi(matches[3], 0),
+				NewLines: atoi(matches[4], 1),
+				Header:   matches[5],
+			})
+			hunk = &hunks[len(hunks)-1]
+			continue
+		}
+		if hunk == nil || line == "" {
+			continue
+		}
+		switch line[0] {
+		case ' ', '+', '-', '\\':
+			hunk.Lines = append(hunk.Lines, line)
+		default:
+			// Start of the next file.
+			hunk = nil
+		}
+	}
+	return hunks, scanner.Err()
+}
+
+// atoi converts a string to an integer, returning a default value if it is empty.
+func atoi(str string, defaultValue int) int {
+	if str == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(str)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiff(t *testing.T) {
+	out := []byte(`diff --git a/main.go b/main.go
+index 3b18e51..a2c4f0e 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@ package main
+ import "fmt"
++import "os"
+ 
+ func main() {
+@@ -10 +11,0 @@ func main() {
+-	os.Exit(0)
+\ No newline at end of file
+`)
+	hunks, err := ParseDiff(out)
+	require.NoError(t, err)
+	assert.Equal(t, []Hunk{{
+		OldStart: 1,
+		OldLines: 3,
+		NewStart: 1,
+		NewLines: 4,
+		Header:   "package main",
+		Lines:    []string{` import "fmt"`, `+import "os"`, " ", " func main() {"},
+	}, {
+		OldStart: 10,
+		OldLines: 1,
+		NewStart: 11,
+		NewLines: 0,
+		Header:   "func main() {",
+		Lines:    []string{"-\tos.Exit(0)", `\ No newline at end of file`},
+	}}, hunks)
+}
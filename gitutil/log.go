@@ -0,0 +1,98 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"container/heap"
+	"io"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+// Log returns an iterator over the commits reachable from a commit ordered from the most recent.
+// Unlike the iterator of go-git, it skips parents that are missing from the
+// repository instead of failing, which happens at the boundary of a shallow clone.
+func Log(repo *git.Repository, from plumbing.Hash) (object.CommitIter, error) {
+	commit, err := repo.CommitObject(from)
+	if err != nil {
+		return nil, err
+	}
+	return &logIter{
+		repo:  repo,
+		queue: &commitQueue{commit},
+		seen:  map[plumbing.Hash]bool{from: true},
+	}, nil
+}
+
+type logIter struct {
+	repo  *git.Repository
+	queue *commitQueue
+	seen  map[plumbing.Hash]bool
+}
+
+func (it *logIter) Next() (*object.Commit, error) {
+	if it.queue.Len() < 1 {
+		return nil, io.EOF
+	}
+	commit := heap.Pop(it.queue).(*object.Commit)
+	for _, hash := range commit.ParentHashes {
+		if it.seen[hash] {
+			continue
+		}
+		it.seen[hash] = true
+		parent, err := it.repo.CommitObject(hash)
+		if err == plumbing.ErrObjectNotFound {
+			// The history is truncated, for instance in a shallow clone.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		heap.Push(it.queue, parent)
+	}
+	return commit, nil
+}
+
+func (it *logIter) ForEach(fn func(*object.Commit) error) error {
+	for {
+		commit, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(commit)
+		if err == storer.ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (it *logIter) Close() {
+	*it.queue = nil
+}
+
+// IsShallow returns whether a repository has a truncated history.
+func IsShallow(repo *git.Repository) (bool, error) {
+	hashes, err := repo.Storer.Shallow()
+	return len(hashes) > 0, err
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestLog(t *testing.T) {
+	storage := memory.NewStorage()
+	repo, err := git.Init(storage, nil)
+	require.NoError(t, err)
+	h := &testHistory{t: t, storage: storage, now: time.Unix(0, 0)}
+
+	// The parent of the root is missing like at the boundary of a shallow clone.
+	missing := plumbing.NewHash("0123456789012345678901234567890123456789")
+	root := h.commit(missing)
+	left := h.commit(root)
+	right := h.commit(root)
+	merge := h.commit(left, right)
+
+	iter, err := Log(repo, merge)
+	require.NoError(t, err)
+	var hashes []plumbing.Hash
+	err = iter.ForEach(func(commit *object.Commit) error {
+		hashes = append(hashes, commit.Hash)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{merge, right, left, root}, hashes)
+}
@@ -16,6 +16,7 @@ package gitutil
 
 import (
 	"bytes"
+	"container/heap"
 	"context"
 
 	git "gopkg.in/src-d/go-git.v4"
@@ -30,7 +31,7 @@ func HasAncestor(ctx context.Context, repo *git.Repository, child plumbing.Hash,
 	if bytes.Equal(child[:], ancestor[:]) {
 		return false, nil
 	}
-	iter, err := repo.Log(&git.LogOptions{From: child})
+	iter, err := Log(repo, child)
 	if err != nil {
 		return false, err
 	}
@@ -50,3 +51,104 @@ func HasAncestor(ctx context.Context, repo *git.Repository, child plumbing.Hash,
 	})
 	return has, err
 }
+
+// AheadBehind returns how many commits are reachable from local but not from remote (ahead), and how many are
+// reachable from remote but not from local (behind). In other words it counts the commits of each side since
+// their merge base.
+func AheadBehind(ctx context.Context, repo *git.Repository, local plumbing.Hash, remote plumbing.Hash) (ahead int, behind int, err error) {
+	if bytes.Equal(local[:], remote[:]) {
+		return 0, 0, nil
+	}
+	// Walk both histories from the most recent commits, marking which side
+	// can reach each commit. The walk stops once every commit left to visit
+	// is reachable from both sides, since their ancestors are too.
+	flags := map[plumbing.Hash]uint8{}
+	queue := &commitQueue{}
+	push := func(hash plumbing.Hash, flag uint8) error {
+		if flags[hash]&flag == flag {
+			return nil
+		}
+		commit, err := repo.CommitObject(hash)
+		if err == plumbing.ErrObjectNotFound {
+			// The history is truncated, for instance in a shallow clone.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		flags[hash] |= flag
+		heap.Push(queue, commit)
+		return nil
+	}
+	if err := push(local, flagLocal); err != nil {
+		return 0, 0, err
+	}
+	if err := push(remote, flagRemote); err != nil {
+		return 0, 0, err
+	}
+	for queue.Len() > 0 && !queue.allFlagged(flags, flagLocal|flagRemote) {
+		select {
+		case <-ctx.Done():
+			return 0, 0, ctx.Err()
+		default:
+		}
+		commit := heap.Pop(queue).(*object.Commit)
+		flag := flags[commit.Hash]
+		for _, parent := range commit.ParentHashes {
+			if err := push(parent, flag); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	for _, flag := range flags {
+		switch flag {
+		case flagLocal:
+			ahead++
+		case flagRemote:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+const (
+	flagLocal uint8 = 1 << iota
+	flagRemote
+)
+
+// commitQueue is a priority queue of commits ordered from the most recent.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int {
+	return len(q)
+}
+
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+
+func (q commitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *commitQueue) Push(x interface{}) {
+	*q = append(*q, x.(*object.Commit))
+}
+
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
+
+// allFlagged returns whether all the commits in the queue have the given flags.
+func (q commitQueue) allFlagged(flags map[plumbing.Hash]uint8, flag uint8) bool {
+	for _, commit := range q {
+		if flags[commit.Hash]&flag != flag {
+			return false
+		}
+	}
+	return true
+}
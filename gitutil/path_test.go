@@ -0,0 +1,88 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+type testHistory struct {
+	t       *testing.T
+	storage *memory.Storage
+	now     time.Time
+}
+
+func (h *testHistory) commit(parents ...plumbing.Hash) plumbing.Hash {
+	h.now = h.now.Add(time.Minute)
+	signature := object.Signature{Name: "test", Email: "test@example.com", When: h.now}
+	commit := object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      "test",
+		ParentHashes: parents,
+	}
+	obj := h.storage.NewEncodedObject()
+	require.NoError(h.t, commit.Encode(obj))
+	hash, err := h.storage.SetEncodedObject(obj)
+	require.NoError(h.t, err)
+	return hash
+}
+
+func TestAheadBehind(t *testing.T) {
+	storage := memory.NewStorage()
+	repo, err := git.Init(storage, nil)
+	require.NoError(t, err)
+	h := &testHistory{t: t, storage: storage, now: time.Unix(0, 0)}
+
+	root := h.commit()
+	base := h.commit(root)
+	local1 := h.commit(base)
+	remote1 := h.commit(base)
+	local2 := h.commit(local1)
+	remote2 := h.commit(remote1)
+	remote3 := h.commit(remote2)
+	merge := h.commit(local2, remote3)
+
+	tests := []struct {
+		name   string
+		local  plumbing.Hash
+		remote plumbing.Hash
+		ahead  int
+		behind int
+	}{
+		{"same", base, base, 0, 0},
+		{"behind", base, remote3, 0, 3},
+		{"ahead", local2, base, 2, 0},
+		{"diverged", local2, remote3, 2, 3},
+		{"merged", merge, remote3, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ahead, behind, err := AheadBehind(context.Background(), repo, tt.local, tt.remote)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ahead, ahead, "ahead")
+			assert.Equal(t, tt.behind, behind, "behind")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Status codes used by git status --porcelain.
+const (
+	StatusUnmodified byte = ' '
+	StatusModified   byte = 'M'
+	StatusTypeChange byte = 'T'
+	StatusAdded      byte = 'A'
+	StatusDeleted    byte = 'D'
+	StatusRenamed    byte = 'R'
+	StatusCopied     byte = 'C'
+	StatusUnmerged   byte = 'U'
+	StatusUntracked  byte = '?'
+	StatusIgnored    byte = '!'
+)
+
+// ErrStatusFormat is returned when the output of git status cannot be parsed.
+var ErrStatusFormat = errors.New("the output of git status is malformed")
+
+// FileStatus is the status of a file as reported by git status --porcelain.
+type FileStatus struct {
+	Path     string
+	OldPath  string
+	Staging  byte
+	Worktree byte
+}
+
+// ParseStatus parses the output of git status --porcelain -z.
+func ParseStatus(out []byte) ([]FileStatus, error) {
+	var files []FileStatus
+	entries := bytes.Split(out, []byte{0})
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) == 0 {
+			continue
+		}
+		if len(entry) < 4 || entry[2] != ' ' {
+			return nil, ErrStatusFormat
+		}
+		file := FileStatus{
+			Path:     string(entry[3:]),
+			Staging:  entry[0],
+			Worktree: entry[1],
+		}
+		// Renamed and copied files are followed by their original path.
+		if file.Staging == StatusRenamed || file.Staging == StatusCopied {
+			i++
+			if i >= len(entries) {
+				return nil, ErrStatusFormat
+			}
+			file.OldPath = string(entries[i])
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
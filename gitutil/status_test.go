@@ -0,0 +1,37 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatus(t *testing.T) {
+	out := []byte(" M main.go\x00A  new.go\x00R  renamed.go\x00old.go\x00?? notes.txt\x00")
+	files, err := ParseStatus(out)
+	require.NoError(t, err)
+	assert.Equal(t, []FileStatus{
+		{Path: "main.go", Staging: StatusUnmodified, Worktree: StatusModified},
+		{Path: "new.go", Staging: StatusAdded, Worktree: StatusUnmodified},
+		{Path: "renamed.go", OldPath: "old.go", Staging: StatusRenamed, Worktree: StatusUnmodified},
+		{Path: "notes.txt", Staging: StatusUntracked, Worktree: StatusUntracked},
+	}, files)
+
+	_, err = ParseStatus([]byte("R  renamed.go"))
+	assert.Equal(t, ErrStatusFormat, err)
+}
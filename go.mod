@@ -11,6 +11,7 @@ require (
 	github.com/cortesi/termlog v0.0.0-20171116205515-87cefd5ac843 // indirect
 	github.com/emirpasic/gods v1.12.0 // indirect
 	github.com/fatih/color v1.7.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/gliderlabs/ssh v0.1.3 // indirect
 	github.com/go-chi/chi v4.0.2+incompatible
 	github.com/golang/mock v1.2.0
@@ -0,0 +1,60 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// CheckoutProject queues a Job to switch a Project to a branch.
+func CheckoutProject(
+	ctx context.Context,
+	projectID string,
+	reference string,
+	create bool,
+	stash bool,
+	highPriority bool,
+) (string, error) {
+	if err := startCheckingOutProject(ctx, projectID); err != nil {
+		return "", err
+	}
+	appCtx := appcontext.Get(ctx)
+	return appCtx.Jobs.Add(ctx, JobNameCheckoutProject, projectID, highPriority, nil, func(ctx context.Context) error {
+		return doCheckoutProject(ctx, projectID, reference, create, stash)
+	}), nil
+}
+
+func startCheckingOutProject(ctx context.Context, projectID string) error {
+	return model.LockProjectE(ctx, projectID, func(project *model.Project) error {
+		if project.IsCheckingOut {
+			return ErrDuplicate
+		}
+		if !project.IsCloned(ctx) {
+			return ErrNotCloned
+		}
+		project.IsCheckingOut = true
+		project.MustStore(ctx)
+		return nil
+	})
+}
+
+func doCheckoutProject(ctx context.Context, projectID, reference string, create, stash bool) error {
+	return model.MustLockProjectE(ctx, projectID, func(project *model.Project) error {
+		return project.Checkout(ctx, reference, create, stash)
+	})
+}
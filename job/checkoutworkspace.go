@@ -0,0 +1,61 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// CheckoutWorkspace queues Jobs to switch the Projects of a Workspace to a branch.
+// If projectIDs isn't empty, only those Projects are switched.
+func CheckoutWorkspace(
+	ctx context.Context,
+	workspaceID string,
+	reference string,
+	create bool,
+	stash bool,
+	projectIDs []string,
+	highPriority bool,
+) ([]string, error) {
+	appCtx := appcontext.Get(ctx)
+	workspace, err := model.LoadWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(projectIDs))
+	for _, projectID := range projectIDs {
+		selected[projectID] = true
+	}
+	var jobIDs []string
+	for _, projectID := range workspace.ProjectsIDs {
+		if len(selected) > 0 && !selected[projectID] {
+			continue
+		}
+		project := model.MustLoadProject(ctx, projectID)
+		if project.IsCheckingOut || !project.IsCloned(ctx) {
+			continue
+		}
+		jobID, err := CheckoutProject(ctx, project.ID, reference, create, stash, highPriority)
+		if err != nil {
+			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "CheckoutWorkspace failed because %s", err.Error())
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, nil
+}
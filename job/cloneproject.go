@@ -27,7 +27,7 @@ func CloneProject(ctx context.Context, projectID string, highPriority bool) (str
 		return "", err
 	}
 	appCtx := appcontext.Get(ctx)
-	return appCtx.Jobs.Add(ctx, JobNameCloneProject, projectID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameCloneProject, projectID, highPriority, nil, func(ctx context.Context) error {
 		return doCloneProject(ctx, projectID)
 	}), nil
 }
@@ -0,0 +1,73 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// CommitProject queues a Job to stage and commit changes in a Project.
+// The function onCommit is called with the created Commit, unless there was nothing to commit.
+func CommitProject(
+	ctx context.Context,
+	projectID string,
+	message string,
+	paths []string,
+	addAll bool,
+	highPriority bool,
+	onCommit func(*model.Commit),
+) (string, error) {
+	if err := startCommittingProject(ctx, projectID); err != nil {
+		return "", err
+	}
+	appCtx := appcontext.Get(ctx)
+	return appCtx.Jobs.Add(ctx, JobNameCommitProject, projectID, highPriority, nil, func(ctx context.Context) error {
+		return doCommitProject(ctx, projectID, message, paths, addAll, onCommit)
+	}), nil
+}
+
+func startCommittingProject(ctx context.Context, projectID string) error {
+	return model.LockProjectE(ctx, projectID, func(project *model.Project) error {
+		if project.IsCommitting {
+			return ErrDuplicate
+		}
+		if !project.IsCloned(ctx) {
+			return ErrNotCloned
+		}
+		project.IsCommitting = true
+		project.MustStore(ctx)
+		return nil
+	})
+}
+
+func doCommitProject(
+	ctx context.Context,
+	projectID string,
+	message string,
+	paths []string,
+	addAll bool,
+	onCommit func(*model.Commit),
+) error {
+	return model.MustLockProjectE(ctx, projectID, func(project *model.Project) error {
+		commit, err := project.Commit(ctx, message, paths, addAll)
+		if commit != nil && onCommit != nil {
+			onCommit(commit)
+		}
+		return err
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"sync"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// CommitProjects queues Jobs to stage and commit the same changes in multiple Projects.
+// It waits for the Jobs to finish and returns the IDs of the created Commits.
+// Projects that have nothing to commit are skipped.
+func CommitProjects(
+	ctx context.Context,
+	projectIDs []string,
+	message string,
+	paths []string,
+	addAll bool,
+	highPriority bool,
+) ([]string, error) {
+	appCtx := appcontext.Get(ctx)
+	var projects []*model.Project
+	for _, projectID := range projectIDs {
+		project, err := model.LoadProject(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	lastMsgID := appCtx.Subs.LastMessageID()
+	var (
+		mu        sync.Mutex
+		jobIDs    []string
+		commitIDs = make(map[string]string)
+	)
+	for _, project := range projects {
+		if project.IsCommitting || !project.IsCloned(ctx) {
+			continue
+		}
+		changes, err := project.Changes(ctx)
+		if err != nil {
+			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "CommitProjects failed because %s", err.Error())
+			continue
+		}
+		if len(changes) < 1 {
+			continue
+		}
+		projectID := project.ID
+		jobID, err := CommitProject(ctx, projectID, message, paths, addAll, highPriority, func(commit *model.Commit) {
+			mu.Lock()
+			commitIDs[projectID] = commit.ID
+			mu.Unlock()
+		})
+		if err != nil {
+			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "CommitProjects failed because %s", err.Error())
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	if err := waitTillJobsDone(ctx, jobIDs, lastMsgID); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var ids []string
+	for _, project := range projects {
+		if commitID, ok := commitIDs[project.ID]; ok {
+			ids = append(ids, commitID)
+		}
+	}
+	return ids, nil
+}
+
+// waitTillJobsDone waits for Jobs to either be done or to have failed.
+func waitTillJobsDone(ctx context.Context, jobIDs []string, lastMsgID uint64) error {
+	if len(jobIDs) < 1 {
+		return nil
+	}
+	pending := make(map[string]bool, len(jobIDs))
+	for _, jobID := range jobIDs {
+		pending[jobID] = true
+	}
+	subsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var mu sync.Mutex
+	subs := appcontext.Get(ctx).Subs
+	subs.Subscribe(subsCtx, model.MessageTypeJobStored, lastMsgID, func(msg interface{}) {
+		job := msg.(*model.Job)
+		switch job.Status {
+		case model.JobStatusDone, model.JobStatusFailed:
+		default:
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		delete(pending, job.ID)
+		if len(pending) < 1 {
+			cancel()
+		}
+	})
+	<-subsCtx.Done()
+	return ctx.Err()
+}
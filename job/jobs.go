@@ -14,14 +14,42 @@
 
 package job
 
+import (
+	"time"
+)
+
 // Job names.
 const (
+	JobNameCheckoutProject     = "Checkout Project"
 	JobNameCloneProject        = "Clone Project"
+	JobNameCommitProject       = "Commit Project"
 	JobNamePullProject         = "Pull Project"
+	JobNamePushProject         = "Push Project"
+	JobNameRestoreProject      = "Restore Project"
 	JobNameRunTask             = "Run Task"
 	JobNameStartService        = "Start Service"
 	JobNameStopService         = "Stop Service"
 	JobNameSyncDirectorySource = "Sync Directory Source"
+	JobNameSyncFromUpstream    = "Sync From Upstream"
 	JobNameSyncGitSource       = "Sync Git Source"
+	JobNameSyncHTTPSource      = "Sync HTTP Source"
 	JobNameSyncProject         = "Sync Project"
 )
+
+// DefaultTimeouts are the default maximum durations of an attempt for each job name.
+var DefaultTimeouts = map[string]time.Duration{
+	JobNameCheckoutProject:     5 * time.Minute,
+	JobNameCloneProject:        30 * time.Minute,
+	JobNameCommitProject:       5 * time.Minute,
+	JobNamePullProject:         10 * time.Minute,
+	JobNamePushProject:         10 * time.Minute,
+	JobNameRestoreProject:      10 * time.Minute,
+	JobNameRunTask:             time.Hour,
+	JobNameStartService:        10 * time.Minute,
+	JobNameStopService:         5 * time.Minute,
+	JobNameSyncDirectorySource: time.Minute,
+	JobNameSyncFromUpstream:    10 * time.Minute,
+	JobNameSyncGitSource:       5 * time.Minute,
+	JobNameSyncHTTPSource:      5 * time.Minute,
+	JobNameSyncProject:         5 * time.Minute,
+}
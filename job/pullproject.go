@@ -27,7 +27,7 @@ func PullProject(ctx context.Context, projectID string, highPriority bool) (stri
 		return "", err
 	}
 	appCtx := appcontext.Get(ctx)
-	return appCtx.Jobs.Add(ctx, JobNamePullProject, projectID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNamePullProject, projectID, highPriority, nil, func(ctx context.Context) error {
 		return doPullProject(ctx, projectID)
 	}), nil
 }
@@ -31,7 +31,8 @@ func PullWorkspace(ctx context.Context, workspaceID string, highPriority bool) (
 	var jobIDs []string
 	for _, projectID := range workspace.ProjectsIDs {
 		project := model.MustLoadProject(ctx, projectID)
-		if project.IsPulling || !project.IsCloned(ctx) || !project.IsBehind {
+		// A Project that has diverged can't be fast-forwarded.
+		if project.IsPulling || !project.IsCloned(ctx) || !project.IsBehind || project.IsDiverged {
 			continue
 		}
 		jobID, err := PullProject(ctx, project.ID, highPriority)
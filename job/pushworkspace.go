@@ -0,0 +1,46 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// PushWorkspace queues Jobs to push all the Projects of a Workspace that are ahead.
+func PushWorkspace(ctx context.Context, workspaceID string, highPriority bool) ([]string, error) {
+	appCtx := appcontext.Get(ctx)
+	workspace, err := model.LoadWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	var jobIDs []string
+	for _, projectID := range workspace.ProjectsIDs {
+		project := model.MustLoadProject(ctx, projectID)
+		// A Project that has diverged would be rejected by the remote.
+		if project.IsPushing || !project.IsCloned(ctx) || !project.IsAhead || project.IsDiverged {
+			continue
+		}
+		jobID, err := PushProject(ctx, project.ID, highPriority)
+		if err != nil {
+			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "PushWorkspace failed because %s", err.Error())
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, nil
+}
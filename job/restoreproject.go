@@ -0,0 +1,45 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// RestoreProject queues a Job to check out a commit saved in a Snapshot.
+func RestoreProject(
+	ctx context.Context,
+	projectID string,
+	hash model.Hash,
+	branch *string,
+	highPriority bool,
+) (string, error) {
+	if err := startCheckingOutProject(ctx, projectID); err != nil {
+		return "", err
+	}
+	appCtx := appcontext.Get(ctx)
+	return appCtx.Jobs.Add(ctx, JobNameRestoreProject, projectID, highPriority, nil, func(ctx context.Context) error {
+		return doRestoreProject(ctx, projectID, hash, branch)
+	}), nil
+}
+
+func doRestoreProject(ctx context.Context, projectID string, hash model.Hash, branch *string) error {
+	return model.MustLockProjectE(ctx, projectID, func(project *model.Project) error {
+		return project.Restore(ctx, hash, branch)
+	})
+}
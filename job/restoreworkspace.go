@@ -0,0 +1,72 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// RestoreWorkspace queues Jobs to check out the commits saved in a Snapshot.
+// Nothing is queued if one of the Projects has uncommitted changes.
+// Projects that no longer exist or aren't cloned are skipped.
+func RestoreWorkspace(ctx context.Context, snapshotID string, highPriority bool) ([]string, error) {
+	appCtx := appcontext.Get(ctx)
+	snapshot, err := model.FindSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := model.LoadWorkspace(ctx, snapshot.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	projects := map[string]*model.Project{}
+	for _, projectID := range workspace.ProjectsIDs {
+		project := model.MustLoadProject(ctx, projectID)
+		if project.IsCloned(ctx) {
+			projects[project.Slug] = project
+		}
+	}
+	for _, projectSnapshot := range snapshot.Projects {
+		project, ok := projects[projectSnapshot.ProjectSlug]
+		if !ok {
+			continue
+		}
+		changes, err := project.Changes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			return nil, model.ErrNotClean
+		}
+	}
+	var jobIDs []string
+	for _, projectSnapshot := range snapshot.Projects {
+		project, ok := projects[projectSnapshot.ProjectSlug]
+		if !ok {
+			appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "%s  project %s of snapshot %s isn't cloned", workspace, projectSnapshot.ProjectSlug, snapshot.Name)
+			continue
+		}
+		jobID, err := RestoreProject(ctx, project.ID, projectSnapshot.Hash, projectSnapshot.Branch, highPriority)
+		if err != nil {
+			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "RestoreWorkspace failed because %s", err.Error())
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, nil
+}
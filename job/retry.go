@@ -0,0 +1,53 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"groundcontrol/model"
+	"groundcontrol/retry"
+)
+
+// SyncRetryPolicy is the retry policy of Jobs that sync Sources and Projects.
+var SyncRetryPolicy = &retry.Policy{
+	MaxAttempts: 3,
+	Backoff:     5 * time.Second,
+	MaxBackoff:  time.Minute,
+	IsRetryable: IsRetryable,
+}
+
+// IsRetryable returns whether a Job that failed with the given error could succeed if retried.
+// Errors are assumed to be transient, such as network errors, unless they are known not to be.
+func IsRetryable(err error) bool {
+	switch err {
+	case context.Canceled,
+		context.DeadlineExceeded,
+		ErrDuplicate,
+		model.ErrNotFound,
+		model.ErrType,
+		model.ErrCyclic,
+		model.ErrAuthentication,
+		transport.ErrAuthenticationRequired,
+		transport.ErrAuthorizationFailed,
+		transport.ErrRepositoryNotFound,
+		transport.ErrInvalidAuthMethod:
+		return false
+	}
+	return true
+}
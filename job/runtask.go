@@ -28,7 +28,7 @@ func RunTask(ctx context.Context, taskID string, env []string, highPriority bool
 	}
 	appCtx := appcontext.Get(ctx)
 	task := model.MustLoadTask(ctx, taskID)
-	return appCtx.Jobs.Add(ctx, JobNameRunTask, task.WorkspaceID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameRunTask, task.WorkspaceID, highPriority, nil, func(ctx context.Context) error {
 		return doRunTask(ctx, taskID, env)
 	}), nil
 }
@@ -28,7 +28,7 @@ func StartService(ctx context.Context, serviceID string, env []string, highPrior
 	}
 	appCtx := appcontext.Get(ctx)
 	service := model.MustLoadService(ctx, serviceID)
-	return appCtx.Jobs.Add(ctx, JobNameStartService, service.WorkspaceID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameStartService, service.WorkspaceID, highPriority, nil, func(ctx context.Context) error {
 		return appCtx.Services.Start(ctx, serviceID, env)
 	}), nil
 }
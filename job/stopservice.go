@@ -28,7 +28,7 @@ func StopService(ctx context.Context, serviceID string, highPriority bool) (stri
 	}
 	appCtx := appcontext.Get(ctx)
 	service := model.MustLoadService(ctx, serviceID)
-	return appCtx.Jobs.Add(ctx, JobNameStopService, service.WorkspaceID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameStopService, service.WorkspaceID, highPriority, nil, func(ctx context.Context) error {
 		return appCtx.Services.Stop(ctx, serviceID)
 	}), nil
 }
@@ -27,7 +27,7 @@ func SyncDirectorySource(ctx context.Context, sourceID string, highPriority bool
 		return "", err
 	}
 	appCtx := appcontext.Get(ctx)
-	return appCtx.Jobs.Add(ctx, JobNameSyncDirectorySource, sourceID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameSyncDirectorySource, sourceID, highPriority, SyncRetryPolicy, func(ctx context.Context) error {
 		return doSyncDirectorySource(ctx, sourceID)
 	}), nil
 }
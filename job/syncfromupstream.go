@@ -0,0 +1,56 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// SyncFromUpstream queues a Job to fast-forward the branch of a Project from its upstream remote.
+func SyncFromUpstream(ctx context.Context, projectID string, highPriority bool) (string, error) {
+	if err := startSyncingFromUpstream(ctx, projectID); err != nil {
+		return "", err
+	}
+	appCtx := appcontext.Get(ctx)
+	return appCtx.Jobs.Add(ctx, JobNameSyncFromUpstream, projectID, highPriority, nil, func(ctx context.Context) error {
+		return doSyncFromUpstream(ctx, projectID)
+	}), nil
+}
+
+func startSyncingFromUpstream(ctx context.Context, projectID string) error {
+	return model.LockProjectE(ctx, projectID, func(project *model.Project) error {
+		if project.IsSyncingFromUpstream {
+			return ErrDuplicate
+		}
+		if !project.IsCloned(ctx) {
+			return ErrNotCloned
+		}
+		if !project.HasUpstream() {
+			return model.ErrNoUpstream
+		}
+		project.IsSyncingFromUpstream = true
+		project.MustStore(ctx)
+		return nil
+	})
+}
+
+func doSyncFromUpstream(ctx context.Context, projectID string) error {
+	return model.MustLockProjectE(ctx, projectID, func(project *model.Project) error {
+		return project.SyncFromUpstream(ctx)
+	})
+}
@@ -27,7 +27,7 @@ func SyncGitSource(ctx context.Context, sourceID string, highPriority bool) (str
 		return "", err
 	}
 	appCtx := appcontext.Get(ctx)
-	return appCtx.Jobs.Add(ctx, JobNameSyncGitSource, sourceID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameSyncGitSource, sourceID, highPriority, SyncRetryPolicy, func(ctx context.Context) error {
 		return doSyncGitSource(ctx, sourceID)
 	}), nil
 }
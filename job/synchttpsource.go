@@ -0,0 +1,50 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+// SyncHTTPSource queues a job to sync the Workspaces of the HTTPSource.
+func SyncHTTPSource(ctx context.Context, sourceID string, highPriority bool) (string, error) {
+	if err := startSyncingHTTPSource(ctx, sourceID); err != nil {
+		return "", err
+	}
+	appCtx := appcontext.Get(ctx)
+	return appCtx.Jobs.Add(ctx, JobNameSyncHTTPSource, sourceID, highPriority, SyncRetryPolicy, func(ctx context.Context) error {
+		return doSyncHTTPSource(ctx, sourceID)
+	}), nil
+}
+
+func startSyncingHTTPSource(ctx context.Context, sourceID string) error {
+	return model.LockHTTPSourceE(ctx, sourceID, func(source *model.HTTPSource) error {
+		if source.IsSyncing {
+			return ErrDuplicate
+		}
+		source.IsSyncing = true
+		source.MustStore(ctx)
+		return nil
+	})
+}
+
+func doSyncHTTPSource(ctx context.Context, sourceID string) error {
+	return model.MustLockHTTPSourceE(ctx, sourceID, func(source *model.HTTPSource) error {
+		return source.Sync(ctx)
+	})
+}
@@ -27,7 +27,7 @@ func SyncProject(ctx context.Context, projectID string, highPriority bool) (stri
 		return "", err
 	}
 	appCtx := appcontext.Get(ctx)
-	return appCtx.Jobs.Add(ctx, JobNameSyncProject, projectID, highPriority, func(ctx context.Context) error {
+	return appCtx.Jobs.Add(ctx, JobNameSyncProject, projectID, highPriority, SyncRetryPolicy, func(ctx context.Context) error {
 		return doSyncProject(ctx, projectID)
 	}), nil
 }
@@ -17,6 +17,7 @@ package job
 import (
 	"context"
 
+	"groundcontrol/appcontext"
 	"groundcontrol/model"
 	"groundcontrol/relay"
 )
@@ -32,6 +33,47 @@ func SyncSource(ctx context.Context, sourceID string, highPriority bool) (string
 		return SyncDirectorySource(ctx, sourceID, highPriority)
 	case model.NodeTypeGitSource:
 		return SyncGitSource(ctx, sourceID, highPriority)
+	case model.NodeTypeHTTPSource:
+		return SyncHTTPSource(ctx, sourceID, highPriority)
 	}
 	return "", model.ErrType
 }
+
+// SyncSourceAndWait queues a Job to sync a Source and waits for it to finish.
+// If the Source is already syncing, the running sync could have read its
+// config before it changed, so it waits for it to finish then queues another
+// one. A sync queued in the meantime by someone else is waited for instead.
+func SyncSourceAndWait(ctx context.Context, sourceID string, highPriority bool) error {
+	subs := appcontext.Get(ctx).Subs
+	for attempt := 0; ; attempt++ {
+		lastMsgID := subs.LastMessageID()
+		jobID, err := SyncSource(ctx, sourceID, highPriority)
+		if err == ErrDuplicate {
+			err = waitTillJobsDone(ctx, activeJobsIDs(ctx, sourceID), lastMsgID)
+			if err != nil || attempt > 0 {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return waitTillJobsDone(ctx, []string{jobID}, lastMsgID)
+	}
+}
+
+// activeJobsIDs returns the IDs of the Jobs of an owner that aren't done or failed.
+func activeJobsIDs(ctx context.Context, ownerID string) []string {
+	system := model.MustLoadSystem(ctx, appcontext.Get(ctx).SystemID)
+	var ids []string
+	for _, id := range system.JobsIDs {
+		job, err := model.LoadJob(ctx, id)
+		if err != nil || job.OwnerID != ownerID {
+			continue
+		}
+		if job.Status != model.JobStatusDone && job.Status != model.JobStatusFailed {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -33,7 +33,7 @@ func SyncWorkspace(ctx context.Context, workspaceID string, highPriority bool) (
 		project := model.MustLoadProject(ctx, projectID)
 		// TODO: It doesn't queue a job if it already has remote commits. This is because the mutation
 		// is called every time a workspace is viewed. It could be handled better.
-		if project.IsSyncing || len(project.RemoteCommitsIDs) > 0 {
+		if project.IsSyncing || len(project.RecentRemoteCommitsIDs) > 0 {
 			continue
 		}
 		jobID, err := SyncProject(ctx, project.ID, highPriority)
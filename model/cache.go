@@ -0,0 +1,200 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"groundcontrol/gitutil"
+)
+
+// cacheLocks serializes operations on the caches of repositories, since they are shared by Projects.
+var cacheLocks sync.Map
+
+// lockCache locks the cache of the repository of the Project until the returned function is called.
+func (n *Project) lockCache(ctx context.Context) func() {
+	actual, _ := cacheLocks.LoadOrStore(n.CachePath(ctx), &sync.Mutex{})
+	mu := actual.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// updateCache clones the cache of the repository if it doesn't exist, otherwise it fetches it.
+// It returns whether the cache has enough history to clone the Project from it.
+func (n *Project) updateCache(ctx context.Context) (bool, error) {
+	unlock := n.lockCache(ctx)
+	defer unlock()
+	if n.IsCached(ctx) {
+		if err := n.fetchCache(ctx); err != nil {
+			return false, err
+		}
+	} else if err := n.cloneCache(ctx); err != nil {
+		return false, err
+	}
+	cache, err := git.PlainOpen(n.CachePath(ctx))
+	if err != nil {
+		return false, err
+	}
+	isShallow, err := gitutil.IsShallow(cache)
+	return !isShallow, err
+}
+
+// fetchCache fetches the cache of the repository.
+func (n *Project) fetchCache(ctx context.Context) error {
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
+	cache, err := git.PlainOpen(n.CachePath(ctx))
+	if err != nil {
+		return err
+	}
+	// The cache is shared, so its history isn't truncated to the depth of a Project.
+	opts := git.FetchOptions{Force: true, Auth: auth}
+	err = cache.FetchContext(ctx, &opts)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return gitError(n.Repository, err)
+	}
+	return nil
+}
+
+// usesCache returns whether the Project is cloned from the cache. The cache has
+// the full history of all the branches, so Projects that limit their depth or
+// clone a single branch are cloned from the remote to download less.
+func (n *Project) usesCache() bool {
+	return n.Depth < 1 && !n.SingleBranch
+}
+
+// cloneFromCache creates the repository of the Project using the objects of the cache.
+// Objects are first read from the cache through Git alternates, then the objects
+// the repository needs are copied and the alternates are removed, like
+// git clone --reference --dissociate does. The repository is independent from
+// the cache, which can be deleted or garbage collected.
+// Only branches can be cloned from the cache, other references are cloned from the remote.
+func (n *Project) cloneFromCache(ctx context.Context) (err error) {
+	refName := plumbing.ReferenceName(n.RemoteReference)
+	if !refName.IsBranch() {
+		return n.cloneFromRemote(ctx)
+	}
+	unlock := n.lockCache(ctx)
+	defer unlock()
+	cache, err := git.PlainOpen(n.CachePath(ctx))
+	if err != nil {
+		return err
+	}
+	remoteRef, err := cache.Reference(n.localRemoteReferenceName(), true)
+	if err != nil {
+		return err
+	}
+	path := n.Path(ctx)
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		return err
+	}
+	// Don't leave a partial repository behind, otherwise the Project would be considered cloned.
+	defer func() {
+		if err != nil {
+			os.RemoveAll(path)
+		}
+	}()
+	alternates := filepath.Join(path, git.GitDirName, "objects", "info", "alternates")
+	if err := os.MkdirAll(filepath.Dir(alternates), 0755); err != nil {
+		return err
+	}
+	// Relative paths would be resolved from the objects directory of the repository.
+	objects, err := filepath.Abs(filepath.Join(n.CachePath(ctx), "objects"))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(alternates, []byte(objects+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := n.copyCacheReferences(cache, repo); err != nil {
+		return err
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:  OriginRemote,
+		URLs:  []string{n.Repository},
+		Fetch: []config.RefSpec{n.fetchRefSpec(OriginRemote)},
+	})
+	if err != nil {
+		return err
+	}
+	err = repo.CreateBranch(&config.Branch{
+		Name:   refName.Short(),
+		Remote: OriginRemote,
+		Merge:  refName,
+	})
+	if err != nil {
+		return err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, remoteRef.Hash())); err != nil {
+		return err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName)); err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Reset(&git.ResetOptions{Mode: git.HardReset, Commit: remoteRef.Hash()}); err != nil {
+		return err
+	}
+	return n.dissociate(ctx, alternates)
+}
+
+// dissociate copies the objects the repository of the Project reads from its
+// alternates, then removes the alternates.
+func (n *Project) dissociate(ctx context.Context, alternates string) error {
+	if err := n.git(ctx, "repack", "-a", "-d", "-q"); err != nil {
+		return err
+	}
+	return os.Remove(alternates)
+}
+
+// copyCacheReferences copies the remote branches and the tags of the cache.
+func (n *Project) copyCacheReferences(cache, repo *git.Repository) error {
+	refs, err := cache.References()
+	if err != nil {
+		return err
+	}
+	defer refs.Close()
+	return refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if ref.Type() != plumbing.HashReference || !name.IsTag() && !name.IsRemote() {
+			return nil
+		}
+		return repo.Storer.SetReference(ref)
+	})
+}
+
+// fetchRefSpec returns the refspec used to fetch a remote of the Project.
+func (n *Project) fetchRefSpec(remote string) config.RefSpec {
+	if n.SingleBranch {
+		refName := plumbing.ReferenceName(n.RemoteReference)
+		return config.RefSpec("+" + n.RemoteReference + ":" + n.remoteReferenceName(remote, refName).String())
+	}
+	return config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, remote))
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+func TestProject_Clone_cacheDepth(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "remote")
+	testCommitFile(t, work, "a.txt", "a\n")
+	testCommitFile(t, work, "b.txt", "b\n")
+	testGit(t, work, "push", "-q", "origin", "master")
+	ctx := newTestContext(dir)
+
+	shallow := newTestProject(ctx, repository)
+	shallow.Depth = 1
+	require.NoError(t, shallow.Clone(ctx))
+	assert.False(t, shallow.IsCached(ctx), "shallow projects are cloned from the remote")
+	assert.True(t, shallow.IsShallow)
+	assert.Equal(t, "1", testGit(t, shallow.Path(ctx), "rev-list", "--count", "HEAD"))
+
+	full := newTestProject(ctx, repository)
+	full.ID = relay.EncodeID(NodeTypeProject, "test", "full")
+	full.Slug = "full"
+	require.NoError(t, full.Clone(ctx))
+	assert.True(t, full.IsCached(ctx))
+	assert.False(t, full.IsShallow)
+	assert.Equal(t, "3", testGit(t, full.Path(ctx), "rev-list", "--count", "HEAD"))
+}
+
+func TestProject_Clone_dissociate(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "remote")
+	testCommitFile(t, work, "a.txt", "a\n")
+	testGit(t, work, "push", "-q", "origin", "master")
+	ctx := newTestContext(dir)
+
+	project := newTestProject(ctx, repository)
+	require.NoError(t, project.Clone(ctx))
+	require.True(t, project.IsCached(ctx))
+	_, err := os.Stat(filepath.Join(project.Path(ctx), ".git", "objects", "info", "alternates"))
+	assert.True(t, os.IsNotExist(err), "the repository doesn't use the cache's objects")
+
+	require.NoError(t, os.RemoveAll(project.CachePath(ctx)))
+	testGit(t, project.Path(ctx), "fsck", "--no-dangling")
+	assert.Equal(t, "2", testGit(t, project.Path(ctx), "rev-list", "--count", "HEAD"))
+}
+
+func TestProject_Clone_relativeCache(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, _ := newTestRemote(t, dir, "remote")
+	ctx := newTestContext(dir)
+	appcontext.Get(ctx).GetRepositoryCachePath = func(string) string {
+		return filepath.Join("cache", "remote.git")
+	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	project := newTestProject(ctx, repository)
+	require.NoError(t, project.Clone(ctx))
+	testGit(t, project.Path(ctx), "log", "-1", "--format=%s")
+	assert.FileExists(t, filepath.Join(project.Path(ctx), "README.md"))
+}
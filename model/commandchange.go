@@ -0,0 +1,185 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// commandsKey identifies a Task or Service across commits.
+type commandsKey struct {
+	Type          CommandChangeType
+	WorkspaceSlug string
+	Name          string
+}
+
+// diffCommands returns the commands of the Tasks and Services that are new or
+// changed between two sets of configs. Removed commands are ignored since they
+// can't run anymore.
+func diffCommands(old, new []*WorkspacesConfig) []*CommandChange {
+	oldCommands, _ := configsCommands(old)
+	newCommands, keys := configsCommands(new)
+	var changes []*CommandChange
+	for _, key := range keys {
+		was, ok := oldCommands[key]
+		now := newCommands[key]
+		if ok && equalStrings(was, now) || !ok && len(now) < 1 {
+			continue
+		}
+		changes = append(changes, &CommandChange{
+			Type:          key.Type,
+			WorkspaceSlug: key.WorkspaceSlug,
+			Name:          key.Name,
+			OldCommands:   was,
+			NewCommands:   now,
+		})
+	}
+	return changes
+}
+
+// configsCommands returns the commands of all the Tasks and Services of
+// configs, and their keys in the order they are defined. The projects the
+// commands run in are included, since changing the repository of a project
+// changes the code run by the same commands. So are the defaults of variables,
+// which are exported to the commands, and the Services and Tasks a Service
+// runs with.
+func configsCommands(configs []*WorkspacesConfig) (map[commandsKey][]string, []commandsKey) {
+	commands := map[commandsKey][]string{}
+	var keys []commandsKey
+	add := func(key commandsKey, values ...string) {
+		if _, ok := commands[key]; !ok {
+			keys = append(keys, key)
+		}
+		commands[key] = append(commands[key], values...)
+	}
+	for _, config := range configs {
+		for _, workspace := range config.Workspaces {
+			projects := map[string]ProjectConfig{}
+			for _, project := range workspace.Projects {
+				projects[project.Slug] = project
+			}
+			for _, task := range workspace.Tasks {
+				key := commandsKey{CommandChangeTypeTask, workspace.Slug, task.Name}
+				add(key, describeVariables(task.Variables)...)
+				for _, step := range task.Steps {
+					for _, slug := range step.Projects {
+						add(key, describeProject(slug, projects))
+					}
+					add(key, step.Commands...)
+				}
+			}
+			for _, service := range workspace.Services {
+				key := commandsKey{CommandChangeTypeService, workspace.Slug, service.Name}
+				add(key, describeVariables(service.Variables)...)
+				for _, name := range service.Needs {
+					add(key, fmt.Sprintf("needs service %s", name))
+				}
+				for _, name := range service.Before {
+					add(key, fmt.Sprintf("runs task %s before starting", name))
+				}
+				for _, name := range service.After {
+					add(key, fmt.Sprintf("runs task %s after exiting", name))
+				}
+				if service.Project != "" {
+					add(key, describeProject(service.Project, projects))
+				}
+				add(key, service.Command)
+				if service.Healthcheck != nil && service.Healthcheck.Command != nil {
+					add(key, *service.Healthcheck.Command)
+				}
+			}
+		}
+	}
+	return commands, keys
+}
+
+// describeProject describes where the code of a project comes from.
+func describeProject(slug string, projects map[string]ProjectConfig) string {
+	project, ok := projects[slug]
+	if !ok {
+		return fmt.Sprintf("in project %s", slug)
+	}
+	description := fmt.Sprintf("in project %s: %s %s", slug, project.Repository, project.Reference)
+	var names []string
+	for name := range project.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		description += fmt.Sprintf(", remote %s %s", name, project.Remotes[name])
+	}
+	return description
+}
+
+// describeVariables describes the variables that have a default value.
+func describeVariables(variables []VariableConfig) []string {
+	var descriptions []string
+	for _, variable := range variables {
+		if variable.Default != nil {
+			descriptions = append(descriptions, fmt.Sprintf("variable %s=%s", variable.Name, *variable.Default))
+		}
+	}
+	return descriptions
+}
+
+// equalStrings returns whether two slices contain the same strings in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadWorkspacesConfigsAtCommit loads the workspaces config files of a commit
+// instead of the files in the working tree.
+func loadWorkspacesConfigsAtCommit(repo *git.Repository, directory string, hash plumbing.Hash) ([]*WorkspacesConfig, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var configs []*WorkspacesConfig
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if path.Ext(file.Name) != ".yml" {
+			return nil
+		}
+		contents, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		config, err := ParseWorkspacesConfigYAML(filepath.Join(directory, file.Name), []byte(contents))
+		if err != nil {
+			return err
+		}
+		configs = append(configs, config)
+		return nil
+	})
+	return configs, err
+}
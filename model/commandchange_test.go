@@ -0,0 +1,217 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCommands(t *testing.T) {
+	healthcheck := "curl localhost"
+	config := func(taskCommands []string, serviceCommand string, healthcheck *string) []*WorkspacesConfig {
+		return []*WorkspacesConfig{{
+			Workspaces: []WorkspaceConfig{{
+				Slug: "workspace",
+				Tasks: []TaskConfig{{
+					Name:  "Task",
+					Steps: []StepConfig{{Commands: taskCommands}},
+				}},
+				Services: []ServiceConfig{{
+					Name:        "Service",
+					Command:     serviceCommand,
+					Healthcheck: &HealthcheckConfig{Command: healthcheck},
+				}},
+			}},
+		}}
+	}
+	projectConfig := func(repository string, remotes map[string]string, stepProjects []string) []*WorkspacesConfig {
+		return []*WorkspacesConfig{{
+			Workspaces: []WorkspaceConfig{{
+				Slug: "workspace",
+				Projects: []ProjectConfig{{
+					Slug:       "project",
+					Repository: repository,
+					Reference:  "refs/heads/master",
+					Remotes:    remotes,
+				}},
+				Tasks: []TaskConfig{{
+					Name:  "Task",
+					Steps: []StepConfig{{Projects: stepProjects, Commands: []string{"make"}}},
+				}},
+			}},
+		}}
+	}
+	serviceConfig := func(service ServiceConfig) []*WorkspacesConfig {
+		service.Name = "Service"
+		service.Command = "serve"
+		return []*WorkspacesConfig{{
+			Workspaces: []WorkspaceConfig{{
+				Slug:     "workspace",
+				Services: []ServiceConfig{service},
+			}},
+		}}
+	}
+	preload := "/tmp/evil.so"
+	path := "/usr/bin"
+	tests := []struct {
+		name string
+		old  []*WorkspacesConfig
+		new  []*WorkspacesConfig
+		want []*CommandChange
+	}{{
+		"unchanged",
+		config([]string{"make"}, "serve", nil),
+		config([]string{"make"}, "serve", nil),
+		nil,
+	}, {
+		"changed task",
+		config([]string{"make"}, "serve", nil),
+		config([]string{"make", "make install"}, "serve", nil),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeTask,
+			WorkspaceSlug: "workspace",
+			Name:          "Task",
+			OldCommands:   []string{"make"},
+			NewCommands:   []string{"make", "make install"},
+		}},
+	}, {
+		"changed health check",
+		config([]string{"make"}, "serve", nil),
+		config([]string{"make"}, "serve", &healthcheck),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeService,
+			WorkspaceSlug: "workspace",
+			Name:          "Service",
+			OldCommands:   []string{"serve"},
+			NewCommands:   []string{"serve", "curl localhost"},
+		}},
+	}, {
+		"new",
+		nil,
+		config(nil, "serve", nil),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeService,
+			WorkspaceSlug: "workspace",
+			Name:          "Service",
+			NewCommands:   []string{"serve"},
+		}},
+	}, {
+		"changed repository",
+		projectConfig("git@example.com:a.git", nil, []string{"project"}),
+		projectConfig("git@example.com:b.git", nil, []string{"project"}),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeTask,
+			WorkspaceSlug: "workspace",
+			Name:          "Task",
+			OldCommands:   []string{"in project project: git@example.com:a.git refs/heads/master", "make"},
+			NewCommands:   []string{"in project project: git@example.com:b.git refs/heads/master", "make"},
+		}},
+	}, {
+		"changed remotes",
+		projectConfig("git@example.com:a.git", nil, []string{"project"}),
+		projectConfig("git@example.com:a.git", map[string]string{"upstream": "git@example.com:b.git"}, []string{"project"}),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeTask,
+			WorkspaceSlug: "workspace",
+			Name:          "Task",
+			OldCommands:   []string{"in project project: git@example.com:a.git refs/heads/master", "make"},
+			NewCommands: []string{
+				"in project project: git@example.com:a.git refs/heads/master, remote upstream git@example.com:b.git",
+				"make",
+			},
+		}},
+	}, {
+		"changed step projects",
+		projectConfig("git@example.com:a.git", nil, nil),
+		projectConfig("git@example.com:a.git", nil, []string{"project"}),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeTask,
+			WorkspaceSlug: "workspace",
+			Name:          "Task",
+			OldCommands:   []string{"make"},
+			NewCommands:   []string{"in project project: git@example.com:a.git refs/heads/master", "make"},
+		}},
+	}, {
+		"unchanged project",
+		projectConfig("git@example.com:a.git", nil, []string{"project"}),
+		projectConfig("git@example.com:a.git", nil, []string{"project"}),
+		nil,
+	}, {
+		"changed variable default",
+		serviceConfig(ServiceConfig{Variables: []VariableConfig{{Name: "PATH", Default: &path}}}),
+		serviceConfig(ServiceConfig{Variables: []VariableConfig{{Name: "LD_PRELOAD", Default: &preload}}}),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeService,
+			WorkspaceSlug: "workspace",
+			Name:          "Service",
+			OldCommands:   []string{"variable PATH=/usr/bin", "serve"},
+			NewCommands:   []string{"variable LD_PRELOAD=/tmp/evil.so", "serve"},
+		}},
+	}, {
+		"variable without default",
+		serviceConfig(ServiceConfig{}),
+		serviceConfig(ServiceConfig{Variables: []VariableConfig{{Name: "PATH"}}}),
+		nil,
+	}, {
+		"changed task variable default",
+		[]*WorkspacesConfig{{Workspaces: []WorkspaceConfig{{
+			Slug:  "workspace",
+			Tasks: []TaskConfig{{Name: "Task", Steps: []StepConfig{{Commands: []string{"make"}}}}},
+		}}}},
+		[]*WorkspacesConfig{{Workspaces: []WorkspaceConfig{{
+			Slug: "workspace",
+			Tasks: []TaskConfig{{
+				Name:      "Task",
+				Variables: []VariableConfig{{Name: "LD_PRELOAD", Default: &preload}},
+				Steps:     []StepConfig{{Commands: []string{"make"}}},
+			}},
+		}}}},
+		[]*CommandChange{{
+			Type:          CommandChangeTypeTask,
+			WorkspaceSlug: "workspace",
+			Name:          "Task",
+			OldCommands:   []string{"make"},
+			NewCommands:   []string{"variable LD_PRELOAD=/tmp/evil.so", "make"},
+		}},
+	}, {
+		"changed needs, before and after",
+		serviceConfig(ServiceConfig{}),
+		serviceConfig(ServiceConfig{Needs: []string{"Database"}, Before: []string{"Build"}, After: []string{"Clean"}}),
+		[]*CommandChange{{
+			Type:          CommandChangeTypeService,
+			WorkspaceSlug: "workspace",
+			Name:          "Service",
+			OldCommands:   []string{"serve"},
+			NewCommands: []string{
+				"needs service Database",
+				"runs task Build before starting",
+				"runs task Clean after exiting",
+				"serve",
+			},
+		}},
+	}, {
+		"removed",
+		config([]string{"make"}, "serve", nil),
+		nil,
+		nil,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffCommands(tt.old, tt.new))
+		})
+	}
+}
@@ -15,13 +15,28 @@
 package model
 
 import (
+	"context"
 	"strings"
+	"sync"
 
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 
 	"groundcontrol/relay"
 )
 
+// MaxRecentCommits is the maximum number of recent Commits of a reference that are stored.
+const MaxRecentCommits = 100
+
+// MaxLogCommits is the maximum number of Commits read from the Git log to paginate Commits that aren't recent.
+const MaxLogCommits = 10000
+
+var (
+	// commitsMu protects commitsRefs.
+	commitsMu sync.Mutex
+	// commitsRefs counts how many times the recent Commits of Projects reference each Commit.
+	commitsRefs = map[string]int{}
+)
+
 // NewCommitFromGit creates a new Commit model from a Git commit.
 func NewCommitFromGit(commit *object.Commit) *Commit {
 	return &Commit{
@@ -38,3 +53,62 @@ func NewCommitFromGit(commit *object.Commit) *Commit {
 func (n *Commit) String() string {
 	return n.Headline
 }
+
+// setRecentCommits stores the recent Commits of the Project then evicts the
+// Commits that no Project references anymore. It also stores the Project.
+func (n *Project) setRecentCommits(ctx context.Context, remoteCommits, localCommits []*Commit) {
+	commitsMu.Lock()
+	defer commitsMu.Unlock()
+	previousIDs := n.recentCommitsIDs()
+	n.RecentRemoteCommitsIDs = storeCommits(ctx, remoteCommits)
+	n.RecentLocalCommitsIDs = storeCommits(ctx, localCommits)
+	n.MustStore(ctx)
+	for _, id := range n.recentCommitsIDs() {
+		commitsRefs[id]++
+	}
+	releaseCommits(ctx, previousIDs)
+}
+
+// recentCommitsIDs returns the IDs of the recent remote and local Commits.
+func (n *Project) recentCommitsIDs() []string {
+	var ids []string
+	ids = append(ids, n.RecentRemoteCommitsIDs...)
+	return append(ids, n.RecentLocalCommitsIDs...)
+}
+
+// storeCommits stores Commits and returns their IDs.
+func storeCommits(ctx context.Context, commits []*Commit) []string {
+	ids := make([]string, len(commits))
+	for i, commit := range commits {
+		commit.MustStore(ctx)
+		ids[i] = commit.ID
+	}
+	return ids
+}
+
+// releaseCommits removes references to Commits and deletes the Commits that
+// are no longer referenced. The caller must hold commitsMu.
+func releaseCommits(ctx context.Context, commitsIDs []string) {
+	for _, id := range commitsIDs {
+		commitsRefs[id]--
+		if commitsRefs[id] > 0 {
+			continue
+		}
+		delete(commitsRefs, id)
+		_ = DeleteCommit(ctx, id)
+	}
+}
+
+// loadCommits loads Commits from the store.
+// It returns ErrNotFound if one of them was evicted in the meantime.
+func loadCommits(ctx context.Context, ids []string) ([]*Commit, error) {
+	commits := make([]*Commit, len(ids))
+	for i, id := range ids {
+		commit, err := LoadCommit(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = commit
+	}
+	return commits, nil
+}
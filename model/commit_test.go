@@ -0,0 +1,97 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+func TestProject_setRecentCommits(t *testing.T) {
+	ctx := newTestContext("")
+	newCommit := func(name string) *Commit {
+		return &Commit{ID: relay.EncodeID(NodeTypeCommit, t.Name(), name), Headline: name}
+	}
+	a := &Project{ID: relay.EncodeID(NodeTypeProject, "test", "a")}
+	b := &Project{ID: relay.EncodeID(NodeTypeProject, "test", "b")}
+	a.MustStore(ctx)
+	b.MustStore(ctx)
+
+	shared, old := newCommit("shared"), newCommit("old")
+	a.setRecentCommits(ctx, []*Commit{shared}, []*Commit{shared, old})
+	b.setRecentCommits(ctx, []*Commit{shared}, nil)
+
+	a.setRecentCommits(ctx, nil, []*Commit{newCommit("new")})
+	_, err := LoadCommit(ctx, old.ID)
+	assert.Equal(t, ErrNotFound, err, "unreferenced Commits are evicted")
+	_, err = LoadCommit(ctx, shared.ID)
+	assert.NoError(t, err, "Commits referenced by another Project are kept")
+
+	require.NoError(t, DeleteProjectRecursive(ctx, b.ID))
+	_, err = LoadCommit(ctx, shared.ID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestProject_LocalCommits(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "remote")
+	for i := 0; i < MaxRecentCommits+4; i++ {
+		testGit(t, work, "commit", "-q", "--allow-empty", "-m", fmt.Sprintf("commit %d", i))
+	}
+	testGit(t, work, "push", "-q", "origin", "master")
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, repository)
+	require.NoError(t, project.Clone(ctx))
+	require.Len(t, project.RecentLocalCommitsIDs, MaxRecentCommits)
+
+	first, last := MaxRecentCommits+2, 2
+	tests := []struct {
+		name  string
+		first *int
+		last  *int
+		want  int
+		// wantLastID is the ID of the last Commit of the page, if any.
+		wantLastID string
+	}{
+		{"first past the recent commits", &first, nil, MaxRecentCommits + 2, ""},
+		{"last without before", nil, &last, 2, project.RecentLocalCommitsIDs[MaxRecentCommits-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := project.LocalCommits(ctx, nil, nil, tt.first, tt.last)
+			require.NoError(t, err)
+			assert.Len(t, page.Edges, tt.want)
+			if tt.wantLastID != "" {
+				assert.Equal(t, tt.wantLastID, page.Edges[len(page.Edges)-1].Node.ID, "only recent Commits are paginated")
+			}
+		})
+	}
+
+	t.Run("evicted", func(t *testing.T) {
+		// A concurrent sync can evict the Commits of a stale copy of the Project.
+		appcontext.Get(ctx).Nodes.Delete(project.RecentLocalCommitsIDs[0])
+		page, err := project.LocalCommits(ctx, nil, nil, &last, nil)
+		require.NoError(t, err)
+		require.Len(t, page.Edges, last)
+		assert.Equal(t, project.RecentLocalCommitsIDs[0], page.Edges[0].Node.ID)
+	})
+}
@@ -33,11 +33,19 @@ func (n *DirectorySource) Sync(ctx context.Context) error {
 	n.IsSyncing = true
 	n.MustStore(ctx)
 
-	workspaceIDs, err := SyncWorkspacesInDirectory(ctx, n.Directory, n.ID)
+	workspaceIDs, conflicts, err := SyncWorkspacesInDirectory(ctx, n.Directory, n)
 	if err != nil {
 		return err
 	}
+	logNewConflicts(ctx, n, n.Conflicts, conflicts)
+	// Workspaces lost to another Source are kept until that Source overwrites them.
+	orphansIDs := subtractIDs(n.WorkspacesIDs, append(workspaceIDs, conflictsWorkspacesIDs(conflicts)...))
+	// Store the Source before deleting the Workspaces that were removed
+	// so that it never references deleted Nodes.
 	n.WorkspacesIDs = workspaceIDs
+	n.Conflicts = conflicts
+	n.MustStore(ctx)
+	n.WorkspacesIDs = append(n.WorkspacesIDs, deleteOrphanedWorkspaces(ctx, n.ID, orphansIDs)...)
 	n.MustStore(ctx)
 	return nil
 }
@@ -20,10 +20,25 @@ import (
 
 // Errors.
 var (
-	ErrNotFound      = errors.New("it wasn't found")
-	ErrType          = errors.New("it has the wrong type")
-	ErrClone         = errors.New("it failed to cloned")
-	ErrFirstNegative = errors.New("first cannot be negative")
-	ErrLastNegative  = errors.New("last cannot be negative")
-	ErrCyclic        = errors.New("there is a cyclic dependency")
+	ErrNotFound             = errors.New("it wasn't found")
+	ErrType                 = errors.New("it has the wrong type")
+	ErrClone                = errors.New("it failed to cloned")
+	ErrFirstNegative        = errors.New("first cannot be negative")
+	ErrLastNegative         = errors.New("last cannot be negative")
+	ErrCyclic               = errors.New("there is a cyclic dependency")
+	ErrBusy                 = errors.New("it is busy")
+	ErrRestartPolicy        = errors.New("the restart policy is invalid")
+	ErrNotClean             = errors.New("there are uncommitted changes, stash them first")
+	ErrAuthentication       = errors.New("authentication to the Git host failed, check its credentials")
+	ErrOtherStagedChanges   = errors.New("changes to other files are staged, commit or unstage them first")
+	ErrNoAuthor             = errors.New("the commit author isn't configured, set user.name and user.email in git or the GIT_AUTHOR_NAME and GIT_AUTHOR_EMAIL keys")
+	ErrNonFastForward       = errors.New("the remote reference has commits that aren't in the local branch, pull first")
+	ErrSnapshotExists       = errors.New("a snapshot with this name already exists")
+	ErrNoUpstream           = errors.New("the project doesn't have an upstream remote")
+	ErrUpstreamDiverged     = errors.New("the branch has commits that aren't upstream so it can't be fast-forwarded")
+	ErrNoURLs               = errors.New("at least one URL is required")
+	ErrNotPending           = errors.New("there is no commit waiting for approval")
+	ErrPendingCommitChanged = errors.New("the commit waiting for approval changed, review its commands again")
+	ErrPinnedCommitNotFound = errors.New("the pinned commit wasn't found in the repository")
+	ErrCommitHash           = errors.New("a commit hash must have 40 hexadecimal characters")
 )
@@ -0,0 +1,93 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/gitutil"
+)
+
+// GitAuthTTL is how long the authentication method of a Git host is reused
+// before its credentials are resolved again, for instance by running the
+// credential helper.
+const GitAuthTTL = 10 * time.Minute
+
+// cachedGitAuth is an authentication method resolved for a Git host.
+type cachedGitAuth struct {
+	auth    transport.AuthMethod
+	expires time.Time
+}
+
+var (
+	// gitAuthsMu protects gitAuths.
+	gitAuthsMu sync.Mutex
+	// gitAuths caches the authentication methods by Git host.
+	gitAuths = map[string]cachedGitAuth{}
+)
+
+// gitAuth returns the authentication method for a Git repository.
+// It returns nil if there are no credentials for the repository.
+// The authentication method is cached per host for GitAuthTTL.
+func gitAuth(ctx context.Context, repo string) (transport.AuthMethod, error) {
+	getGitAuth := appcontext.Get(ctx).GetGitAuth
+	if getGitAuth == nil {
+		return nil, nil
+	}
+	host := gitHost(repo)
+	gitAuthsMu.Lock()
+	cached, ok := gitAuths[host]
+	gitAuthsMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.auth, nil
+	}
+	auth, err := getGitAuth(ctx, repo)
+	if err != nil {
+		return nil, gitError(repo, err)
+	}
+	gitAuthsMu.Lock()
+	gitAuths[host] = cachedGitAuth{auth: auth, expires: time.Now().Add(GitAuthTTL)}
+	gitAuthsMu.Unlock()
+	return auth, nil
+}
+
+// gitHost returns the host of a Git repository.
+// It returns the repository itself if it isn't a valid endpoint.
+func gitHost(repo string) string {
+	endpoint, err := transport.NewEndpoint(repo)
+	if err != nil {
+		return repo
+	}
+	return endpoint.Host
+}
+
+// gitError converts authentication errors returned by go-git to ErrAuthentication
+// so that they can be told apart from network errors. It also forgets the
+// authentication method of the host of the repository so that the credentials
+// are resolved again on the next attempt.
+func gitError(repo string, err error) error {
+	if gitutil.IsAuthError(err) {
+		gitAuthsMu.Lock()
+		delete(gitAuths, gitHost(repo))
+		gitAuthsMu.Unlock()
+		return ErrAuthentication
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/pubsub"
+	"groundcontrol/relay"
+	"groundcontrol/store"
+)
+
+// testGit runs a Git command in a directory and returns its trimmed output.
+func testGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(
+		os.Environ(),
+		"GIT_AUTHOR_NAME=Test",
+		"GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test",
+		"GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// testCommitFile writes a file then commits it in a working copy.
+func testCommitFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	testGit(t, dir, "add", name)
+	testGit(t, dir, "commit", "-q", "-m", "update "+name)
+	return testGit(t, dir, "rev-parse", "HEAD")
+}
+
+// newTestRemote creates a bare repository with one commit on master.
+// It returns the path to the repository and to a working copy that can push to it.
+func newTestRemote(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	work := filepath.Join(dir, name+"-work")
+	repository := filepath.Join(dir, name+".git")
+	testGit(t, dir, "init", "-q", "-b", "master", work)
+	testCommitFile(t, work, "README.md", "# "+name+"\n")
+	testGit(t, dir, "clone", "-q", "--bare", work, repository)
+	testGit(t, work, "remote", "add", "origin", repository)
+	testGit(t, work, "fetch", "-q", "origin")
+	testGit(t, work, "branch", "-q", "--set-upstream-to", "origin/master")
+	return repository, work
+}
+
+// testLog discards log entries since the logger can't be imported by models.
+type testLog struct{}
+
+func (testLog) Debug(context.Context, string, ...interface{}) string   { return "" }
+func (testLog) Info(context.Context, string, ...interface{}) string    { return "" }
+func (testLog) Warning(context.Context, string, ...interface{}) string { return "" }
+func (testLog) Error(context.Context, string, ...interface{}) string   { return "" }
+func (testLog) DebugWithOwner(context.Context, string, string, ...interface{}) string {
+	return ""
+}
+func (testLog) InfoWithOwner(context.Context, string, string, ...interface{}) string {
+	return ""
+}
+func (testLog) WarningWithOwner(context.Context, string, string, ...interface{}) string {
+	return ""
+}
+func (testLog) ErrorWithOwner(context.Context, string, string, ...interface{}) string {
+	return ""
+}
+
+// newTestContext creates an app context that keeps its files in a directory.
+func newTestContext(dir string) context.Context {
+	return appcontext.With(context.Background(), &appcontext.Context{
+		Nodes: store.NewMemory(),
+		Log:   testLog{},
+		Subs:  pubsub.New(100),
+		GetProjectPath: func(workspaceSlug, projectSlug string) string {
+			return filepath.Join(dir, "workspaces", workspaceSlug, projectSlug)
+		},
+		GetRepositoryCachePath: func(repo string) string {
+			return filepath.Join(dir, "cache", fmt.Sprintf("%x.git", sha1.Sum([]byte(repo))))
+		},
+		GetSnapshotsPath: func(workspaceSlug string) string {
+			return filepath.Join(dir, "snapshots", workspaceSlug)
+		},
+		GetGitSourcePath: func(repo, reference string) string {
+			return filepath.Join(dir, "sources", fmt.Sprintf("%x", sha1.Sum([]byte(repo+reference))))
+		},
+	})
+}
+
+// newTestProject stores a Project tracking the master branch of a repository.
+func newTestProject(ctx context.Context, repository string) *Project {
+	workspace := &Workspace{
+		ID:   relay.EncodeID(NodeTypeWorkspace, "test"),
+		Slug: "test",
+		Name: "Test",
+	}
+	project := &Project{
+		ID:              relay.EncodeID(NodeTypeProject, "test", "project"),
+		Slug:            "project",
+		Repository:      repository,
+		Reference:       "refs/heads/master",
+		RemoteReference: "refs/heads/master",
+		LocalReference:  "refs/heads/master",
+		WorkspaceID:     workspace.ID,
+	}
+	workspace.ProjectsIDs = []string{project.ID}
+	workspace.MustStore(ctx)
+	project.MustStore(ctx)
+	return project
+}
+
+// newTestDir creates a temporary directory that is removed when the test ends.
+func newTestDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "groundcontrol")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestGitAuth_cache(t *testing.T) {
+	defer func() {
+		gitAuthsMu.Lock()
+		delete(gitAuths, "example.com")
+		gitAuthsMu.Unlock()
+	}()
+	calls := 0
+	ctx := appcontext.With(context.Background(), &appcontext.Context{
+		GetGitAuth: func(context.Context, string) (transport.AuthMethod, error) {
+			calls++
+			return &http.BasicAuth{Username: "user", Password: "password"}, nil
+		},
+	})
+
+	_, err := gitAuth(ctx, "https://example.com/a.git")
+	require.NoError(t, err)
+	_, err = gitAuth(ctx, "https://example.com/b.git")
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "the host was cached")
+
+	assert.Equal(t, ErrAuthentication, gitError("https://example.com/a.git", transport.ErrAuthorizationFailed))
+	_, err = gitAuth(ctx, "https://example.com/b.git")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "the host was invalidated")
+}
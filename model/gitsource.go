@@ -15,8 +15,11 @@
 package model
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"path/filepath"
+	"time"
 
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
@@ -49,9 +52,17 @@ func (n *GitSource) Path(ctx context.Context) string {
 }
 
 // Sync syncs the Source.
-func (n *GitSource) Sync(ctx context.Context) error {
+func (n *GitSource) Sync(ctx context.Context) (err error) {
 	defer func() {
 		n.IsSyncing = false
+		if err != nil {
+			lastSyncError := err.Error()
+			n.LastSyncError = &lastSyncError
+		} else {
+			lastSyncedAt := DateTime(time.Now())
+			n.LastSyncedAt = &lastSyncedAt
+			n.LastSyncError = nil
+		}
 		n.MustStore(ctx)
 	}()
 	n.IsSyncing = true
@@ -60,31 +71,203 @@ func (n *GitSource) Sync(ctx context.Context) error {
 	if err := n.pullOrClone(ctx); err != nil {
 		return err
 	}
-	workspaceIDs, err := SyncWorkspacesInDirectory(ctx, n.Path(ctx), n.ID)
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := n.headHash(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if err := n.review(ctx, repo, head); err != nil {
+		return err
+	}
+	var approvedHash plumbing.Hash
+	copy(approvedHash[:], *n.ApprovedCommit)
+	configs, err := loadWorkspacesConfigsAtCommit(repo, n.Path(ctx), approvedHash)
+	if err == plumbing.ErrObjectNotFound {
+		// The approved commit is gone, so keep the current Workspaces until
+		// the pending commit is approved.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	loadedCommit := Hash(approvedHash[:])
+	n.HeadCommit = &loadedCommit
+	var (
+		workspaceIDs []string
+		conflicts    []*SourceConflict
+	)
+	for _, config := range configs {
+		ids, found, err := config.storeNodes(ctx, n)
+		if err != nil {
+			return err
+		}
+		workspaceIDs = append(workspaceIDs, ids...)
+		conflicts = append(conflicts, found...)
+	}
+	logNewConflicts(ctx, n, n.Conflicts, conflicts)
+	// Workspaces lost to another Source are kept until that Source overwrites them.
+	orphansIDs := subtractIDs(n.WorkspacesIDs, append(workspaceIDs, conflictsWorkspacesIDs(conflicts)...))
+	// Store the Source before deleting the Workspaces that were removed
+	// so that it never references deleted Nodes.
 	n.WorkspacesIDs = workspaceIDs
+	n.Conflicts = conflicts
+	n.MustStore(ctx)
+	n.WorkspacesIDs = append(n.WorkspacesIDs, deleteOrphanedWorkspaces(ctx, n.ID, orphansIDs)...)
+	n.MustStore(ctx)
+	return nil
+}
+
+// IsPendingApproval indicates whether a commit is waiting for approval.
+func (n *GitSource) IsPendingApproval() bool {
+	return n.PendingCommit != nil
+}
+
+// Approve approves the commands of the pending commit.
+// The commit must be the pending commit, so that commands that were never
+// reviewed can't be approved if a newer commit was synced in the meantime.
+// The Source must be synced again to use them.
+func (n *GitSource) Approve(ctx context.Context, commit Hash) error {
+	if n.PendingCommit == nil {
+		return ErrNotPending
+	}
+	if !bytes.Equal(*n.PendingCommit, commit) {
+		return ErrPendingCommitChanged
+	}
+	return n.approve(ctx, commit)
+}
+
+// approve approves a commit and saves it to the sources config.
+func (n *GitSource) approve(ctx context.Context, hash Hash) error {
+	appCtx := appcontext.Get(ctx)
+	appCtx.Sources.ApproveGitSource(ctx, n.ID, hex.EncodeToString(hash))
+	if err := appCtx.Sources.Save(); err != nil {
+		return err
+	}
+	n.ApprovedCommit = &hash
+	n.PendingCommit = nil
+	n.CommandChanges = nil
+	n.MustStore(ctx)
+	return nil
+}
+
+// review approves the commit at HEAD unless it has new or changed commands,
+// in which case it becomes the pending commit and the approved commit keeps
+// being used.
+func (n *GitSource) review(ctx context.Context, repo *git.Repository, head plumbing.Hash) error {
+	headCommit := Hash(head[:])
+	if n.ApprovedCommit == nil {
+		// Adding the Source is an explicit decision to trust its current commands.
+		return n.approve(ctx, headCommit)
+	}
+	if bytes.Equal(*n.ApprovedCommit, headCommit) {
+		n.PendingCommit = nil
+		n.CommandChanges = nil
+		return nil
+	}
+	var approvedHash plumbing.Hash
+	copy(approvedHash[:], *n.ApprovedCommit)
+	old, err := loadWorkspacesConfigsAtCommit(repo, n.Path(ctx), approvedHash)
+	if err == plumbing.ErrObjectNotFound {
+		// The approved commit is gone, for instance after a force-push,
+		// so all the commands must be approved again.
+		old, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	new, err := loadWorkspacesConfigsAtCommit(repo, n.Path(ctx), head)
+	if err != nil {
+		return err
+	}
+	changes := diffCommands(old, new)
+	if len(changes) < 1 {
+		return n.approve(ctx, headCommit)
+	}
+	if n.PendingCommit == nil || !bytes.Equal(*n.PendingCommit, headCommit) {
+		appcontext.Get(ctx).Log.WarningWithOwner(
+			ctx,
+			n.ID,
+			"%s has new or changed commands that must be approved before they are used",
+			n,
+		)
+	}
+	n.PendingCommit = &headCommit
+	n.CommandChanges = changes
 	n.MustStore(ctx)
 	return nil
 }
 
+// headHash returns the hash of the commit the Workspaces should be loaded
+// from, which is the pinned commit if there is one.
+func (n *GitSource) headHash(ctx context.Context, repo *git.Repository) (plumbing.Hash, error) {
+	if n.PinnedCommit == nil {
+		head, err := repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return head.Hash(), nil
+	}
+	var hash plumbing.Hash
+	copy(hash[:], *n.PinnedCommit)
+	_, err := repo.CommitObject(hash)
+	if err == plumbing.ErrObjectNotFound {
+		// The commit could be on another branch or newer than the clone.
+		if err := n.fetch(ctx, repo); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		_, err = repo.CommitObject(hash)
+	}
+	if err == plumbing.ErrObjectNotFound {
+		return plumbing.ZeroHash, ErrPinnedCommitNotFound
+	}
+	return hash, err
+}
+
 // pullOrClone pulls the directory if already cloned, otherwise it clones it.
+// A pinned Source isn't pulled since it doesn't follow its Reference.
 func (n *GitSource) pullOrClone(ctx context.Context) error {
 	if n.IsCloned(ctx) {
+		if n.PinnedCommit != nil {
+			return nil
+		}
 		return n.pull(ctx)
 	}
 	return n.clone(ctx)
 }
 
+// fetch fetches all the branches of the remote repository.
+func (n *GitSource) fetch(ctx context.Context, repo *git.Repository) error {
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
+	opts := git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+	}
+	err = repo.FetchContext(ctx, &opts)
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return gitError(n.Repository, err)
+}
+
 // clone clones the remote repository.
 func (n *GitSource) clone(ctx context.Context) error {
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
 	opts := git.CloneOptions{
 		URL:           n.Repository,
 		ReferenceName: plumbing.ReferenceName(n.Reference),
+		Auth:          auth,
 	}
-	_, err := git.PlainCloneContext(ctx, n.Path(ctx), false, &opts)
-	return err
+	_, err = git.PlainCloneContext(ctx, n.Path(ctx), false, &opts)
+	return gitError(n.Repository, err)
 }
 
 // pull pulls the remote repository.
@@ -97,15 +280,20 @@ func (n *GitSource) pull(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
 	opts := git.PullOptions{
 		RemoteName:    "origin",
 		ReferenceName: plumbing.ReferenceName(n.Reference),
+		Auth:          auth,
 	}
 	err = worktree.PullContext(ctx, &opts)
 	if err == git.NoErrAlreadyUpToDate {
 		return nil
 	}
-	return err
+	return gitError(n.Repository, err)
 }
 
 // openRepository opens the repository of the source.
@@ -0,0 +1,116 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/relay"
+)
+
+func TestGitSource_Sync_approvedCommitNotFound(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "workspaces")
+	testCommitFile(t, work, "workspaces.yml", `workspaces:
+  - slug: test
+    name: Test
+    services:
+      - name: Serve
+        command: serve
+`)
+	testGit(t, work, "push", "-q", "origin", "master")
+	head := testGit(t, work, "rev-parse", "HEAD")
+	ctx := newTestContext(dir)
+
+	// The approved commit was lost, for instance after a force-push.
+	missing, err := hex.DecodeString("5f3c1a9e8b7d6c4f2a1e0d9c8b7a6f5e4d3c2b1a")
+	require.NoError(t, err)
+	approvedCommit := Hash(missing)
+	source := &GitSource{
+		ID:             relay.EncodeID(NodeTypeGitSource, repository, "refs/heads/master"),
+		Repository:     repository,
+		Reference:      "refs/heads/master",
+		ApprovedCommit: &approvedCommit,
+	}
+	source.MustStore(ctx)
+
+	require.NoError(t, source.Sync(ctx))
+	require.NotNil(t, source.PendingCommit)
+	assert.Equal(t, head, hex.EncodeToString(*source.PendingCommit))
+	assert.Equal(t, []*CommandChange{{
+		Type:          CommandChangeTypeService,
+		WorkspaceSlug: "test",
+		Name:          "Serve",
+		NewCommands:   []string{"serve"},
+	}}, source.CommandChanges)
+	assert.Nil(t, source.LastSyncError)
+	assert.Empty(t, source.WorkspacesIDs)
+
+	t.Run("approve another commit", func(t *testing.T) {
+		assert.Equal(t, ErrPendingCommitChanged, source.Approve(ctx, approvedCommit))
+	})
+}
+
+func TestGitSource_Sync_pending(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "workspaces")
+	approved := testCommitFile(t, work, "workspaces.yml", `workspaces:
+  - slug: test
+    name: Test
+`)
+	head := testCommitFile(t, work, "workspaces.yml", `workspaces:
+  - slug: test
+    name: Test
+    services:
+      - name: Serve
+        command: serve
+`)
+	testGit(t, work, "push", "-q", "origin", "master")
+	ctx := newTestContext(dir)
+
+	approvedHash, err := hex.DecodeString(approved)
+	require.NoError(t, err)
+	approvedCommit := Hash(approvedHash)
+	source := &GitSource{
+		ID:             relay.EncodeID(NodeTypeGitSource, repository, "refs/heads/master"),
+		Repository:     repository,
+		Reference:      "refs/heads/master",
+		ApprovedCommit: &approvedCommit,
+	}
+	source.MustStore(ctx)
+
+	require.NoError(t, source.Sync(ctx))
+	require.NotNil(t, source.PendingCommit)
+	assert.Equal(t, head, hex.EncodeToString(*source.PendingCommit))
+	require.NotNil(t, source.HeadCommit)
+	assert.Equal(t, approved, hex.EncodeToString(*source.HeadCommit), "the Workspaces are loaded from the approved commit")
+}
+
+func TestGitSource_Approve_notPending(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	ctx := newTestContext(dir)
+	commit, err := hex.DecodeString("5f3c1a9e8b7d6c4f2a1e0d9c8b7a6f5e4d3c2b1a")
+	require.NoError(t, err)
+
+	source := &GitSource{}
+	assert.Equal(t, ErrNotPending, source.Approve(ctx, Hash(commit)))
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/util"
+)
+
+// httpCacheMeta contains the validators of a downloaded file.
+type httpCacheMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// String is a string representation for the type instance.
+func (n *HTTPSource) String() string {
+	if len(n.URLs) < 1 {
+		return ""
+	}
+	u, err := url.Parse(n.URLs[0])
+	if err != nil {
+		return n.URLs[0]
+	}
+	return u.Host + u.Path
+}
+
+// Sync syncs the Source.
+func (n *HTTPSource) Sync(ctx context.Context) error {
+	defer func() {
+		n.IsSyncing = false
+		n.MustStore(ctx)
+	}()
+	n.IsSyncing = true
+	n.MustStore(ctx)
+
+	var (
+		workspaceIDs []string
+		conflicts    []*SourceConflict
+	)
+	for _, u := range n.URLs {
+		filename, err := downloadHTTPSourceFile(ctx, u)
+		if err != nil {
+			return err
+		}
+		config, err := LoadWorkspacesConfigYAML(filename)
+		if err != nil {
+			return err
+		}
+		ids, found, err := config.storeNodes(ctx, n)
+		if err != nil {
+			return err
+		}
+		workspaceIDs = append(workspaceIDs, ids...)
+		conflicts = append(conflicts, found...)
+	}
+	logNewConflicts(ctx, n, n.Conflicts, conflicts)
+	// Workspaces lost to another Source are kept until that Source overwrites them.
+	orphansIDs := subtractIDs(n.WorkspacesIDs, append(workspaceIDs, conflictsWorkspacesIDs(conflicts)...))
+	// Store the Source before deleting the Workspaces that were removed
+	// so that it never references deleted Nodes.
+	n.WorkspacesIDs = workspaceIDs
+	n.Conflicts = conflicts
+	n.MustStore(ctx)
+	n.WorkspacesIDs = append(n.WorkspacesIDs, deleteOrphanedWorkspaces(ctx, n.ID, orphansIDs)...)
+	n.MustStore(ctx)
+	return nil
+}
+
+// downloadHTTPSourceFile downloads a file to the cache unless the cached copy
+// is still valid according to its ETag and Last-Modified headers.
+// It returns the path to the cached file.
+func downloadHTTPSourceFile(ctx context.Context, u string) (string, error) {
+	filename := appcontext.Get(ctx).GetHTTPSourcePath(u)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	var meta httpCacheMeta
+	if util.FileExists(filename) {
+		meta = readHTTPCacheMeta(filename)
+	}
+	if meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.LastModified)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if meta != (httpCacheMeta{}) {
+			return filename, nil
+		}
+	case http.StatusOK:
+		return filename, writeHTTPSourceFile(filename, resp)
+	}
+	return "", fmt.Errorf("%s responded with %s", u, resp.Status)
+}
+
+// readHTTPCacheMeta reads the validators of a cached file.
+// It returns empty validators if they can't be read.
+func readHTTPCacheMeta(filename string) httpCacheMeta {
+	var meta httpCacheMeta
+	bytes, err := ioutil.ReadFile(filename + ".json")
+	if err != nil {
+		return httpCacheMeta{}
+	}
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return httpCacheMeta{}
+	}
+	return meta
+}
+
+// writeHTTPSourceFile writes the body of a response to the cache along with its validators.
+func writeHTTPSourceFile(filename string, resp *http.Response) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a failed download never
+	// leaves a truncated file in the cache.
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		return err
+	}
+	meta := httpCacheMeta{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	bytes, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename+".json", bytes, 0644)
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/appcontext"
+)
+
+// statusWriter counts the responses that have a body.
+type statusWriter struct {
+	http.ResponseWriter
+	bodies *int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if status == http.StatusOK {
+		*w.bodies++
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestDownloadHTTPSourceFile(t *testing.T) {
+	modTime := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		etag         string
+		lastModified bool
+		wantBodies   int
+	}{{
+		"no validators",
+		"",
+		false,
+		2,
+	}, {
+		"ETag",
+		`"v1"`,
+		false,
+		1,
+	}, {
+		"Last-Modified",
+		"",
+		true,
+		1,
+	}, {
+		"ETag and Last-Modified",
+		`"v1"`,
+		true,
+		1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				var lastModified time.Time
+				if tt.lastModified {
+					lastModified = modTime
+				}
+				http.ServeContent(&statusWriter{w, &bodies}, r, "workspaces.yml", lastModified, strings.NewReader("workspaces: []\n"))
+			}))
+			defer server.Close()
+
+			dir, err := ioutil.TempDir("", "httpsource")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			ctx := appcontext.With(context.Background(), &appcontext.Context{
+				GetHTTPSourcePath: func(string) string {
+					return filepath.Join(dir, "workspaces.yml")
+				},
+			})
+
+			for i := 0; i < 2; i++ {
+				filename, err := downloadHTTPSourceFile(ctx, server.URL+"/workspaces.yml")
+				require.NoError(t, err)
+				bytes, err := ioutil.ReadFile(filename)
+				require.NoError(t, err)
+				assert.Equal(t, "workspaces: []\n", string(bytes))
+			}
+
+			assert.Equal(t, tt.wantBodies, bodies)
+		})
+	}
+}
+
+func TestDownloadHTTPSourceFile_error(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "httpsource")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := appcontext.With(context.Background(), &appcontext.Context{
+		GetHTTPSourcePath: func(string) string {
+			return filepath.Join(dir, "workspaces.yml")
+		},
+	})
+
+	_, err = downloadHTTPSourceFile(ctx, server.URL+"/workspaces.yml")
+	assert.EqualError(t, err, server.URL+"/workspaces.yml responded with 404 Not Found")
+}
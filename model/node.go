@@ -38,3 +38,24 @@ func MustLoadNode(ctx context.Context, id string) store.Node {
 	}
 	return node
 }
+
+// subtractIDs returns the IDs that are in ids but not in remove.
+func subtractIDs(ids, remove []string) []string {
+	set := map[string]bool{}
+	for _, id := range remove {
+		set[id] = true
+	}
+	var diff []string
+	for _, id := range ids {
+		if !set[id] {
+			diff = append(diff, id)
+		}
+	}
+	return diff
+}
+
+// logDeletionPostponed logs that a Node removed from its Source isn't deleted yet because it is busy.
+func logDeletionPostponed(ctx context.Context, name string) {
+	appCtx := appcontext.Get(ctx)
+	appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "%s deletion postponed because it is busy", name)
+}
@@ -15,20 +15,27 @@
 package model
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
 
 	"groundcontrol/appcontext"
 	"groundcontrol/gitutil"
+	"groundcontrol/shell"
 	"groundcontrol/util"
 )
 
@@ -85,7 +92,38 @@ func (n *Project) IsCached(ctx context.Context) bool {
 // CachePath returns the path to the Project's cache.
 func (n *Project) CachePath(ctx context.Context) string {
 	appCtx := appcontext.Get(ctx)
-	return appCtx.GetProjectCachePath(n.Workspace(ctx).Slug, n.Slug)
+	return appCtx.GetRepositoryCachePath(n.Repository)
+}
+
+// IsBusy returns whether the Project is being cloned, pulled, pushed, checked out, committed, or synced.
+func (n *Project) IsBusy() bool {
+	return n.IsCloning ||
+		n.IsPulling ||
+		n.IsPushing ||
+		n.IsCheckingOut ||
+		n.IsCommitting ||
+		n.IsSyncing ||
+		n.IsSyncingFromUpstream
+}
+
+// DeleteProjectRecursive deletes a Project.
+// The Commits are not deleted since other Projects can reference them.
+// It returns ErrBusy if the Project is being cloned, pulled, or synced.
+func DeleteProjectRecursive(ctx context.Context, id string) error {
+	project, err := LoadProject(ctx, id)
+	if err != nil {
+		return err
+	}
+	if project.IsBusy() {
+		return ErrBusy
+	}
+	if err := DeleteProject(ctx, id); err != nil {
+		return err
+	}
+	commitsMu.Lock()
+	defer commitsMu.Unlock()
+	releaseCommits(ctx, project.recentCommitsIDs())
+	return nil
 }
 
 // Clone clones and store the Project.
@@ -97,15 +135,38 @@ func (n *Project) Clone(ctx context.Context) error {
 	n.IsCloning = true
 	n.MustStore(ctx)
 
-	path := n.Path(ctx)
-	refName := plumbing.ReferenceName(n.RemoteReference)
-	opts := git.CloneOptions{URL: n.Repository, ReferenceName: refName}
-	if _, err := git.PlainCloneContext(ctx, path, false, &opts); err != nil {
+	if err := n.cloneRepository(ctx); err != nil {
 		return err
 	}
 	return n.Sync(ctx)
 }
 
+// cloneRepository clones the repository of the Project from the cache if it can, otherwise from the remote.
+func (n *Project) cloneRepository(ctx context.Context) error {
+	if !n.usesCache() {
+		return n.cloneFromRemote(ctx)
+	}
+	canUseCache, err := n.updateCache(ctx)
+	if err != nil {
+		return err
+	}
+	if canUseCache {
+		return n.cloneFromCache(ctx)
+	}
+	return n.cloneFromRemote(ctx)
+}
+
+// cloneFromRemote clones the repository of the Project without using the cache.
+func (n *Project) cloneFromRemote(ctx context.Context) error {
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
+	opts := n.cloneOptions(auth)
+	_, err = git.PlainCloneContext(ctx, n.Path(ctx), false, &opts)
+	return gitError(n.Repository, err)
+}
+
 // Pull pulls and stores the Project.
 func (n *Project) Pull(ctx context.Context) error {
 	defer func() {
@@ -123,18 +184,338 @@ func (n *Project) Pull(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
 	refName := plumbing.ReferenceName(n.RemoteReference)
-	opts := git.PullOptions{RemoteName: "origin", ReferenceName: refName}
+	opts := git.PullOptions{RemoteName: OriginRemote, ReferenceName: refName, Depth: n.Depth, Auth: auth}
 	err = worktree.PullContext(ctx, &opts)
 	if err == git.NoErrAlreadyUpToDate {
 		return nil
 	}
 	if err != nil {
+		return gitError(n.Repository, err)
+	}
+	return n.Sync(ctx)
+}
+
+// Push pushes the local branch to its remote reference then syncs the Project.
+// It returns ErrNonFastForward if the remote reference has commits that aren't in the local branch.
+func (n *Project) Push(ctx context.Context) error {
+	defer func() {
+		n.IsPushing = false
+		n.MustStore(ctx)
+	}()
+	n.IsPushing = true
+	n.MustStore(ctx)
+
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	// Sync even if the remote was already up-to-date, since it could have been pushed from elsewhere.
+	if err := n.push(ctx, repo); err != nil {
 		return err
 	}
 	return n.Sync(ctx)
 }
 
+// push pushes the local branch to its remote reference on origin.
+// It succeeds if the remote reference was already up-to-date.
+func (n *Project) push(ctx context.Context, repo *git.Repository) error {
+	if err := n.checkNotBehind(ctx, repo); err != nil {
+		return err
+	}
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", n.LocalReference, n.RemoteReference))
+	opts := git.PushOptions{RemoteName: OriginRemote, RefSpecs: []config.RefSpec{refSpec}, Auth: auth}
+	err = repo.PushContext(ctx, &opts)
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	if err == git.ErrForceNeeded {
+		return ErrNonFastForward
+	}
+	return gitError(n.Repository, err)
+}
+
+// checkNotBehind fetches the remote then returns ErrNonFastForward if the
+// remote reference has commits that aren't in the local branch.
+// A remote reference that doesn't exist yet is never behind.
+func (n *Project) checkNotBehind(ctx context.Context, repo *git.Repository) error {
+	if err := n.fetch(ctx); err != nil {
+		return err
+	}
+	remoteRef, err := repo.Reference(n.localRemoteReferenceName(), true)
+	if err == plumbing.ErrReferenceNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	localRef, err := repo.Reference(plumbing.ReferenceName(n.LocalReference), true)
+	if err != nil {
+		return err
+	}
+	_, behind, err := gitutil.AheadBehind(ctx, repo, localRef.Hash(), remoteRef.Hash())
+	if err != nil {
+		return err
+	}
+	if behind > 0 {
+		return ErrNonFastForward
+	}
+	return nil
+}
+
+// Checkout switches the Project to a branch then syncs the Project.
+// The reference can either be a branch name or a full reference name.
+// If create is true, the branch is created from the current commit and tracks a remote branch of the same name.
+// It returns ErrNotClean if there are uncommitted changes, unless stash is true.
+func (n *Project) Checkout(ctx context.Context, reference string, create, stash bool) error {
+	defer func() {
+		n.IsCheckingOut = false
+		n.MustStore(ctx)
+	}()
+	n.IsCheckingOut = true
+	n.MustStore(ctx)
+
+	isClean, err := n.checkIfClean(ctx)
+	if err != nil {
+		return err
+	}
+	if !isClean {
+		if !stash {
+			return ErrNotClean
+		}
+		if err := n.stash(ctx); err != nil {
+			return err
+		}
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	refName := plumbing.ReferenceName(reference)
+	if !strings.HasPrefix(reference, "refs/") {
+		refName = plumbing.NewBranchReferenceName(reference)
+	}
+	opts := git.CheckoutOptions{Branch: refName, Create: create}
+	if !create {
+		// Create a local branch if only the remote has it.
+		_, err := repo.Reference(refName, true)
+		if err == plumbing.ErrReferenceNotFound {
+			remoteRef, err := repo.Reference(n.remoteReferenceName(OriginRemote, refName), true)
+			if err != nil {
+				return err
+			}
+			opts.Create = true
+			opts.Hash = remoteRef.Hash()
+		} else if err != nil {
+			return err
+		}
+	}
+	if opts.Create && refName.IsBranch() {
+		err := repo.CreateBranch(&config.Branch{
+			Name:   refName.Short(),
+			Remote: OriginRemote,
+			Merge:  refName,
+		})
+		if err != nil && err != git.ErrBranchExists {
+			return err
+		}
+	}
+	if err := worktree.Checkout(&opts); err != nil {
+		return err
+	}
+	return n.syncCheckedOut(ctx, repo)
+}
+
+// syncCheckedOut syncs the Project after HEAD was moved.
+func (n *Project) syncCheckedOut(ctx context.Context, repo *git.Repository) error {
+	if err := n.syncReferences(ctx); err != nil {
+		return err
+	}
+	_, err := repo.Reference(n.localRemoteReferenceName(), true)
+	if err == plumbing.ErrReferenceNotFound {
+		// The branch isn't on the remote yet so there is nothing to compare it to.
+		return n.syncLocalOnly(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return n.Sync(ctx)
+}
+
+// Commit stages changes then commits them and syncs the Project.
+// If addAll is true, all changes are staged, otherwise only the given paths are staged.
+// Since the whole index is committed, it returns ErrOtherStagedChanges if paths are given
+// and changes to other files are already staged.
+// It returns nil if there is nothing to commit.
+func (n *Project) Commit(ctx context.Context, message string, paths []string, addAll bool) (*Commit, error) {
+	defer func() {
+		n.IsCommitting = false
+		n.MustStore(ctx)
+	}()
+	n.IsCommitting = true
+	n.MustStore(ctx)
+
+	if !addAll && len(paths) > 0 {
+		if err := n.checkOnlyPathsStaged(ctx, paths); err != nil {
+			return nil, err
+		}
+	}
+	if addAll || len(paths) > 0 {
+		args := append([]string{"add", "--all", "--"}, paths...)
+		if err := n.git(ctx, args...); err != nil {
+			return nil, err
+		}
+	}
+	statuses, err := n.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !hasStagedChanges(statuses) {
+		return nil, nil
+	}
+	author, err := n.author(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := worktree.Commit(message, &git.CommitOptions{Author: author})
+	if err != nil {
+		return nil, err
+	}
+	gitCommit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	commit := NewCommitFromGit(gitCommit)
+	commit.MustStore(ctx)
+	if _, err := repo.Reference(n.localRemoteReferenceName(), true); err == plumbing.ErrReferenceNotFound {
+		return commit, n.syncLocalOnly(ctx)
+	}
+	return commit, n.Sync(ctx)
+}
+
+// author returns the signature to use for new commits.
+// The identity comes from the Git config, or from the GIT_AUTHOR_NAME and GIT_AUTHOR_EMAIL keys.
+func (n *Project) author(ctx context.Context) (*object.Signature, error) {
+	name := n.gitConfig(ctx, "user.name")
+	email := n.gitConfig(ctx, "user.email")
+	if name == "" || email == "" {
+		keys := appcontext.Get(ctx).Keys.All()
+		name = keys["GIT_AUTHOR_NAME"]
+		email = keys["GIT_AUTHOR_EMAIL"]
+	}
+	if name == "" || email == "" {
+		return nil, ErrNoAuthor
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+// gitConfig returns the value of a Git config option, or an empty string if it isn't set.
+func (n *Project) gitConfig(ctx context.Context, name string) string {
+	cmd := exec.CommandContext(ctx, "git", "config", "--get", name)
+	cmd.Dir = n.Path(ctx)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// checkOnlyPathsStaged returns ErrOtherStagedChanges if files outside of the given paths have staged changes.
+func (n *Project) checkOnlyPathsStaged(ctx context.Context, paths []string) error {
+	all, err := n.status(ctx)
+	if err != nil {
+		return err
+	}
+	inPaths, err := n.status(ctx, paths...)
+	if err != nil {
+		return err
+	}
+	if countStagedChanges(all) > countStagedChanges(inPaths) {
+		return ErrOtherStagedChanges
+	}
+	return nil
+}
+
+// countStagedChanges returns the number of files that have changes in the index.
+func countStagedChanges(statuses []gitutil.FileStatus) int {
+	count := 0
+	for _, status := range statuses {
+		if isStaged(status) {
+			count++
+		}
+	}
+	return count
+}
+
+// isStaged returns whether a file has changes in the index.
+func isStaged(status gitutil.FileStatus) bool {
+	switch status.Staging {
+	case gitutil.StatusUnmodified, gitutil.StatusUntracked, gitutil.StatusIgnored:
+		return false
+	}
+	return true
+}
+
+// hasStagedChanges returns whether some of the files have changes in the index.
+func hasStagedChanges(statuses []gitutil.FileStatus) bool {
+	return countStagedChanges(statuses) > 0
+}
+
+// stash stashes uncommitted changes, including untracked files.
+func (n *Project) stash(ctx context.Context) error {
+	return n.git(ctx, "stash", "push", "--include-untracked")
+}
+
+// git runs a Git command in the repository of the Project.
+// The output of the command is included in the error if it fails.
+func (n *Project) git(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = n.Path(ctx)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// syncLocalOnly syncs the Project when the local branch doesn't exist on the remote.
+func (n *Project) syncLocalOnly(ctx context.Context) error {
+	localCommits, err := n.loadRecentCommits(ctx, plumbing.ReferenceName(n.LocalReference))
+	if err != nil {
+		return err
+	}
+	n.setRecentCommits(ctx, nil, localCommits)
+	n.IsBehind = false
+	n.IsAhead = false
+	n.BehindCount = 0
+	n.AheadCount = 0
+	n.IsDiverged = false
+	n.IsClean, err = n.checkIfClean(ctx)
+	if err != nil {
+		return err
+	}
+	return n.syncUpstreamStatus(ctx)
+}
+
 // Sync syncs the Project with Git.
 func (n *Project) Sync(ctx context.Context) error {
 	defer func() {
@@ -147,20 +528,114 @@ func (n *Project) Sync(ctx context.Context) error {
 	if err := n.syncReferences(ctx); err != nil {
 		return err
 	}
-	if err := n.fetchOrClone(ctx); err != nil {
+	remoteHash, err := n.listRemoteHash(ctx, n.Repository)
+	if err != nil {
 		return err
 	}
-	remoteCommitsIDs, err := n.loadCommits(ctx, n.localRemoteReferenceName())
+	if remoteHash.IsZero() && n.IsCloned(ctx) {
+		// The branch isn't on the remote yet so there is nothing to fetch.
+		if err := n.syncLocalOnly(ctx); err != nil {
+			return err
+		}
+		lastSyncedAt := DateTime(time.Now())
+		n.LastSyncedAt = &lastSyncedAt
+		return nil
+	}
+	var remoteCommits []*Commit
+	isFetched := n.isFetched(ctx, remoteHash)
+	if isFetched {
+		// The remote reference hasn't moved so there is nothing to fetch.
+		remoteCommits, err = loadCommits(ctx, n.RecentRemoteCommitsIDs)
+	} else {
+		err = n.fetchOrClone(ctx)
+	}
+	if !isFetched || err == ErrNotFound {
+		remoteCommits, err = n.loadRecentCommits(ctx, n.localRemoteReferenceName())
+	}
 	if err != nil {
 		return err
 	}
-	n.RemoteCommitsIDs = remoteCommitsIDs
-	localCommitsIDs, err := n.loadCommits(ctx, plumbing.ReferenceName(n.LocalReference))
-	if err != nil {
+	// There are no local Commits until the Project is cloned.
+	localCommits := remoteCommits
+	if n.IsCloned(ctx) {
+		localCommits, err = n.loadRecentCommits(ctx, plumbing.ReferenceName(n.LocalReference))
+		if err != nil {
+			return err
+		}
+	}
+	if err := n.syncShallow(ctx); err != nil {
+		return err
+	}
+	if err := n.fetchUpstream(ctx); err != nil {
+		// The status relative to origin is still useful if upstream can't be reached.
+		appcontext.Get(ctx).Log.WarningWithOwner(ctx, n.ID, "could not fetch upstream because %s", err.Error())
+	}
+	n.setRecentCommits(ctx, remoteCommits, localCommits)
+	if err := n.syncStatus(ctx); err != nil {
 		return err
 	}
-	n.LocalCommitsIDs = localCommitsIDs
-	return n.syncStatus(ctx)
+	lastRemoteHash := Hash(remoteHash[:])
+	lastSyncedAt := DateTime(time.Now())
+	n.LastRemoteHash = &lastRemoteHash
+	n.LastSyncedAt = &lastSyncedAt
+	return nil
+}
+
+// syncShallow sets whether the history of the repository is truncated.
+func (n *Project) syncShallow(ctx context.Context) error {
+	repo, err := n.openRepository(ctx)
+	if err != nil || repo == nil {
+		return err
+	}
+	n.IsShallow, err = gitutil.IsShallow(repo)
+	return err
+}
+
+// listRemoteHash lists the references of a remote repository to find the hash of the remote reference.
+// It is much cheaper than fetching. It returns a zero hash if the remote reference doesn't exist.
+func (n *Project) listRemoteHash(ctx context.Context, url string) (plumbing.Hash, error) {
+	auth, err := gitAuth(ctx, url)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	// The remote is created in an in-memory repository since the Project might not be cloned yet.
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	remote, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: OriginRemote,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return plumbing.ZeroHash, gitError(url, err)
+	}
+	for _, ref := range refs {
+		if ref.Name().String() == n.RemoteReference {
+			return ref.Hash(), nil
+		}
+	}
+	return plumbing.ZeroHash, nil
+}
+
+// isFetched returns whether the remote reference was already fetched at the given hash.
+func (n *Project) isFetched(ctx context.Context, remoteHash plumbing.Hash) bool {
+	if remoteHash.IsZero() || n.LastRemoteHash == nil || len(n.RecentRemoteCommitsIDs) < 1 {
+		return false
+	}
+	if !bytes.Equal(*n.LastRemoteHash, remoteHash[:]) {
+		return false
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil || repo == nil {
+		return false
+	}
+	ref, err := repo.Reference(n.localRemoteReferenceName(), true)
+	return err == nil && ref.Hash() == remoteHash
 }
 
 // EnsureCloned guarantees the Project to be cloned by the time it returns.
@@ -201,12 +676,13 @@ func (n *Project) waitTillCloned(ctx context.Context, lastMsgID uint64) error {
 	return nil
 }
 
-// fetchOrClone fetches the repo if cloned or cached, otherwise it clones it.
+// fetchOrClone fetches the repo if cloned, otherwise it updates the cache.
 func (n *Project) fetchOrClone(ctx context.Context) error {
-	if !n.IsCloned(ctx) && !n.IsCached(ctx) {
-		return n.cloneCache(ctx)
+	if n.IsCloned(ctx) {
+		return n.fetch(ctx)
 	}
-	return n.fetch(ctx)
+	_, err := n.updateCache(ctx)
+	return err
 }
 
 // openRepository opens the repository of the project.
@@ -223,12 +699,30 @@ func (n *Project) openRepository(ctx context.Context) (*git.Repository, error) {
 }
 
 // cloneCache bare clones the repository into the cache.
+// All the branches and their full history are cloned since the cache is shared
+// by the Projects tracking the repository.
 func (n *Project) cloneCache(ctx context.Context) error {
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
 	cachePath := n.CachePath(ctx)
-	refName := plumbing.ReferenceName(n.RemoteReference)
-	opts := git.CloneOptions{URL: n.Repository, ReferenceName: refName}
-	_, err := git.PlainCloneContext(ctx, cachePath, true, &opts)
-	return err
+	opts := n.cloneOptions(auth)
+	opts.SingleBranch = false
+	opts.Depth = 0
+	_, err = git.PlainCloneContext(ctx, cachePath, true, &opts)
+	return gitError(n.Repository, err)
+}
+
+// cloneOptions returns the options to clone the repository of the Project.
+func (n *Project) cloneOptions(auth transport.AuthMethod) git.CloneOptions {
+	return git.CloneOptions{
+		URL:           n.Repository,
+		ReferenceName: plumbing.ReferenceName(n.RemoteReference),
+		SingleBranch:  n.SingleBranch,
+		Depth:         n.Depth,
+		Auth:          auth,
+	}
 }
 
 // fetch fetches either the cloned or the cached repository.
@@ -237,37 +731,142 @@ func (n *Project) fetch(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	opts := git.FetchOptions{Force: !n.IsCloned(ctx)}
+	auth, err := gitAuth(ctx, n.Repository)
+	if err != nil {
+		return err
+	}
+	opts := git.FetchOptions{Force: !n.IsCloned(ctx), Depth: n.Depth, Auth: auth}
 	err = repo.FetchContext(ctx, &opts)
 	if err != nil && err != git.NoErrAlreadyUpToDate {
-		return err
+		return gitError(n.Repository, err)
 	}
 	return nil
 }
 
-// loadCommits loads the commits of a reference.
-func (n *Project) loadCommits(ctx context.Context, refName plumbing.ReferenceName) ([]string, error) {
+// loadRecentCommits loads the most recent commits of a reference.
+// It loads at most MaxRecentCommits commits.
+func (n *Project) loadRecentCommits(ctx context.Context, refName plumbing.ReferenceName) ([]*Commit, error) {
 	iter, err := n.iterateCommits(ctx, refName)
 	if err != nil {
 		return nil, err
 	}
 	defer iter.Close()
 
-	var commitIDs []string
-	return commitIDs, iter.ForEach(func(c *object.Commit) error {
+	var commits []*Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		commits = append(commits, NewCommitFromGit(c))
+		if len(commits) >= MaxRecentCommits {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return commits, err
+}
+
+// RemoteCommits lists the remote Commits using Relay pagination.
+func (n *Project) RemoteCommits(ctx context.Context, after, before *string, first, last *int) (*CommitConnection, error) {
+	refName := n.localRemoteReferenceName()
+	return n.paginateCommits(ctx, refName, n.RecentRemoteCommitsIDs, after, before, first, last)
+}
+
+// LocalCommits lists the local Commits using Relay pagination.
+func (n *Project) LocalCommits(ctx context.Context, after, before *string, first, last *int) (*CommitConnection, error) {
+	refName := plumbing.ReferenceName(n.LocalReference)
+	return n.paginateCommits(ctx, refName, n.RecentLocalCommitsIDs, after, before, first, last)
+}
+
+// paginateCommits lists the Commits of a reference using Relay pagination.
+// The recent Commits are loaded from the store. The Git log is only read if
+// the page goes past them, and the Commits that are read aren't stored.
+// Since it would have to read the whole Git log, a page that doesn't start
+// from the most recent Commit or end before a cursor only contains recent
+// Commits. At most MaxLogCommits Commits are read from the Git log.
+func (n *Project) paginateCommits(
+	ctx context.Context,
+	refName plumbing.ReferenceName,
+	recentIDs []string,
+	after, before *string,
+	first, last *int,
+) (*CommitConnection, error) {
+	isRepository := n.IsCloned(ctx) || n.IsCached(ctx)
+	slice, err := loadCommits(ctx, recentIDs)
+	if err == ErrNotFound {
+		// The Project was synced in the meantime, so read the recent Commits from the Git log again.
+		slice, recentIDs = nil, nil
+		if isRepository {
+			slice, err = n.loadRecentCommits(ctx, refName)
+		}
+		for _, commit := range slice {
+			recentIDs = append(recentIDs, commit.ID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	recent := make(map[string]bool, len(recentIDs))
+	for _, id := range recentIDs {
+		recent[id] = true
+	}
+	isComplete := len(recentIDs) < MaxRecentCommits || !isRepository
+	isBounded := first != nil || before != nil
+	if isComplete || !isBounded || hasEnoughCommits(slice, after, before, first, last) {
+		return PaginateCommitSlice(slice, after, before, first, last, nil)
+	}
+	iter, err := n.iterateCommits(ctx, refName)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	err = iter.ForEach(func(c *object.Commit) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
 		commit := NewCommitFromGit(c)
-		commit.MustStore(ctx)
-		commitIDs = append(commitIDs, commit.ID)
+		if recent[commit.ID] {
+			return nil
+		}
+		slice = append(slice, commit)
+		if len(slice) >= MaxLogCommits || hasEnoughCommits(slice, after, before, first, last) {
+			return storer.ErrStop
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return PaginateCommitSlice(slice, after, before, first, last, nil)
+}
+
+// hasEnoughCommits returns whether a slice of Commits, which starts at the
+// most recent Commit, contains all the Commits needed to paginate it.
+func hasEnoughCommits(slice []*Commit, after, before *string, first, last *int) bool {
+	if before != nil {
+		return indexOfCommitInSlice(slice, *before) >= 0
+	}
+	if last != nil || first == nil {
+		return false
+	}
+	start := 0
+	if after != nil {
+		index := indexOfCommitInSlice(slice, *after)
+		if index < 0 {
+			return false
+		}
+		start = index + 1
+	}
+	// One more Commit is needed to know whether there is a next page.
+	return len(slice)-start > *first
 }
 
 // syncReferences sets the local and remote references according to the current branch.
+// If there isn't one, the local reference is HEAD and the remote reference is the Reference.
 func (n *Project) syncReferences(ctx context.Context) error {
 	branch, err := n.currentBranch(ctx)
 	if err != nil {
@@ -280,6 +879,11 @@ func (n *Project) syncReferences(ctx context.Context) error {
 	}
 	n.RemoteReference = n.Reference
 	n.LocalReference = n.Reference
+	if n.IsCloned(ctx) {
+		// HEAD is detached, for instance after a Snapshot was restored, so
+		// compare the commit that is checked out rather than the Reference.
+		n.LocalReference = plumbing.HEAD.String()
+	}
 	return nil
 }
 
@@ -288,8 +892,11 @@ func (n *Project) syncStatus(ctx context.Context) error {
 	if !n.IsCloned(ctx) {
 		n.IsBehind = false
 		n.IsAhead = false
+		n.BehindCount = 0
+		n.AheadCount = 0
+		n.IsDiverged = false
 		n.IsClean = true
-		return nil
+		return n.syncUpstreamStatus(ctx)
 	}
 	repo, err := n.openRepository(ctx)
 	if err != nil {
@@ -303,16 +910,18 @@ func (n *Project) syncStatus(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	n.IsBehind, err = gitutil.HasAncestor(ctx, repo, remoteRef.Hash(), localRef.Hash())
+	n.AheadCount, n.BehindCount, err = gitutil.AheadBehind(ctx, repo, localRef.Hash(), remoteRef.Hash())
 	if err != nil {
 		return err
 	}
-	n.IsAhead, err = gitutil.HasAncestor(ctx, repo, localRef.Hash(), remoteRef.Hash())
+	n.IsBehind = n.BehindCount > 0
+	n.IsAhead = n.AheadCount > 0
+	n.IsDiverged = n.IsBehind && n.IsAhead
+	n.IsClean, err = n.checkIfClean(ctx)
 	if err != nil {
 		return err
 	}
-	n.IsClean, err = n.checkIfClean(ctx)
-	return err
+	return n.syncUpstreamStatus(ctx)
 }
 
 // checkIfClean checks if there are uncommited changes.
@@ -324,6 +933,121 @@ func (n *Project) checkIfClean(ctx context.Context) (bool, error) {
 	return len(out) < 1, err
 }
 
+// SyncIsClean checks if there are uncommitted changes and stores the Project if IsClean changed.
+func (n *Project) SyncIsClean(ctx context.Context) error {
+	if !n.IsCloned(ctx) {
+		return nil
+	}
+	isClean, err := n.checkIfClean(ctx)
+	if err != nil || isClean == n.IsClean {
+		return err
+	}
+	n.IsClean = isClean
+	n.MustStore(ctx)
+	return nil
+}
+
+// Changes returns the files that have uncommitted changes.
+// It returns nil if the Project isn't cloned.
+func (n *Project) Changes(ctx context.Context) ([]FileChange, error) {
+	if !n.IsCloned(ctx) {
+		return nil, nil
+	}
+	statuses, err := n.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]FileChange, len(statuses))
+	for i, status := range statuses {
+		changes[i] = FileChange{
+			Path:     status.Path,
+			Staging:  fileStatus(status.Staging),
+			Worktree: fileStatus(status.Worktree),
+		}
+		if status.OldPath != "" {
+			oldPath := status.OldPath
+			changes[i].OldPath = &oldPath
+		}
+	}
+	return changes, nil
+}
+
+// Diff returns the uncommitted changes of a file as unified diff hunks.
+// Changes to tracked files are relative to HEAD, so they include staged changes.
+// It returns nil if the Project isn't cloned.
+func (n *Project) Diff(ctx context.Context, path string) ([]DiffHunk, error) {
+	if !n.IsCloned(ctx) {
+		return nil, nil
+	}
+	statuses, err := n.status(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"diff", "HEAD", "--", path}
+	if len(statuses) == 1 && statuses[0].Staging == gitutil.StatusUntracked {
+		args = []string{"diff", "--no-index", "--", os.DevNull, path}
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = n.Path(ctx)
+	out, err := cmd.Output()
+	// With --no-index, git exits with one when there are differences.
+	if exitCode := shell.ExitCode(err); exitCode != nil && *exitCode == 1 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := gitutil.ParseDiff(out)
+	if err != nil {
+		return nil, err
+	}
+	hunks := make([]DiffHunk, len(parsed))
+	for i, hunk := range parsed {
+		hunks[i] = DiffHunk{
+			OldStart: hunk.OldStart,
+			OldLines: hunk.OldLines,
+			NewStart: hunk.NewStart,
+			NewLines: hunk.NewLines,
+			Header:   hunk.Header,
+			Lines:    hunk.Lines,
+		}
+	}
+	return hunks, nil
+}
+
+// status returns the status of the files that have uncommitted changes, optionally limited to some paths.
+func (n *Project) status(ctx context.Context, paths ...string) ([]gitutil.FileStatus, error) {
+	args := append([]string{"status", "--porcelain", "-z", "--"}, paths...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = n.Path(ctx)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return gitutil.ParseStatus(out)
+}
+
+// fileStatus converts a status code of git status --porcelain to a FileStatus.
+func fileStatus(code byte) FileStatus {
+	switch code {
+	case gitutil.StatusModified, gitutil.StatusTypeChange:
+		return FileStatusModified
+	case gitutil.StatusAdded:
+		return FileStatusAdded
+	case gitutil.StatusDeleted:
+		return FileStatusDeleted
+	case gitutil.StatusRenamed:
+		return FileStatusRenamed
+	case gitutil.StatusCopied:
+		return FileStatusCopied
+	case gitutil.StatusUnmerged:
+		return FileStatusUnmerged
+	case gitutil.StatusUntracked:
+		return FileStatusUntracked
+	}
+	return FileStatusUnmodified
+}
+
 // iterateCommits creates an iterator for the commits of a reference.
 func (n *Project) iterateCommits(ctx context.Context, refName plumbing.ReferenceName) (object.CommitIter, error) {
 	repo, err := n.openRepository(ctx)
@@ -334,8 +1058,7 @@ func (n *Project) iterateCommits(ctx context.Context, refName plumbing.Reference
 	if err != nil {
 		return nil, err
 	}
-	opts := git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime}
-	return repo.Log(&opts)
+	return gitutil.Log(repo, ref.Hash())
 }
 
 // currentBranch returns the current branch of the repository.
@@ -353,7 +1076,12 @@ func (n *Project) currentBranch(ctx context.Context) (*config.Branch, error) {
 
 // localRemoteReferenceName returns the name of the local reference that points to the remote.
 func (n *Project) localRemoteReferenceName() plumbing.ReferenceName {
-	parts := strings.Split(n.RemoteReference, "/")
+	return n.remoteReferenceName(OriginRemote, plumbing.ReferenceName(n.RemoteReference))
+}
+
+// remoteReferenceName returns the name of the local reference that points to a reference of a remote.
+func (n *Project) remoteReferenceName(remote string, refName plumbing.ReferenceName) plumbing.ReferenceName {
+	parts := strings.Split(refName.String(), "/")
 	name := strings.Join(parts[2:], "/")
-	return plumbing.NewRemoteReferenceName("origin", name)
+	return plumbing.NewRemoteReferenceName(remote, name)
 }
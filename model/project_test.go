@@ -0,0 +1,127 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject_Sync_localBranch(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, _ := newTestRemote(t, dir, "remote")
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, repository)
+
+	require.NoError(t, project.Clone(ctx))
+	require.NoError(t, project.Checkout(ctx, "feature", true, false))
+	testCommitFile(t, project.Path(ctx), "feature.txt", "feature\n")
+
+	require.NoError(t, project.Sync(ctx), "the branch isn't on the remote")
+	assert.Equal(t, "refs/heads/feature", project.LocalReference)
+	assert.Len(t, project.RecentLocalCommitsIDs, 2)
+	assert.Empty(t, project.RecentRemoteCommitsIDs)
+	assert.False(t, project.IsAhead)
+	assert.True(t, project.IsClean)
+}
+
+func TestProject_Push_alreadyPushed(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, _ := newTestRemote(t, dir, "remote")
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, repository)
+
+	require.NoError(t, project.Clone(ctx))
+	testCommitFile(t, project.Path(ctx), "a.txt", "a\n")
+	require.NoError(t, project.Sync(ctx))
+	assert.Equal(t, 1, project.AheadCount)
+
+	// Push outside of the app.
+	testGit(t, project.Path(ctx), "push", "-q", "origin", "master")
+
+	require.NoError(t, project.Push(ctx))
+	assert.Equal(t, 0, project.AheadCount)
+	assert.False(t, project.IsAhead)
+}
+
+func TestProject_Push_behind(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "remote")
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, repository)
+
+	require.NoError(t, project.Clone(ctx))
+	testCommitFile(t, project.Path(ctx), "a.txt", "a\n")
+
+	// Push a commit the local branch lacks outside of the app.
+	testCommitFile(t, work, "b.txt", "b\n")
+	testGit(t, work, "push", "-q", "origin", "master")
+
+	assert.Equal(t, ErrNonFastForward, project.Push(ctx))
+}
+
+func TestProject_Commit_paths(t *testing.T) {
+	tests := []struct {
+		name    string
+		staged  string
+		wantErr error
+	}{{
+		"nothing else staged",
+		"",
+		nil,
+	}, {
+		"path already staged",
+		"a.txt",
+		nil,
+	}, {
+		"other path staged",
+		"b.txt",
+		ErrOtherStagedChanges,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, clean := newTestDir(t)
+			defer clean()
+			repository, _ := newTestRemote(t, dir, "remote")
+			ctx := newTestContext(dir)
+			project := newTestProject(ctx, repository)
+			require.NoError(t, project.Clone(ctx))
+			path := project.Path(ctx)
+			testGit(t, path, "config", "user.name", "Test")
+			testGit(t, path, "config", "user.email", "test@example.com")
+			require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.txt"), []byte("a\n"), 0644))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(path, "b.txt"), []byte("b\n"), 0644))
+			if tt.staged != "" {
+				testGit(t, path, "add", tt.staged)
+			}
+
+			commit, err := project.Commit(ctx, "add a", []string{"a.txt"}, false)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, commit)
+			assert.Equal(t, "a.txt", testGit(t, path, "show", "--name-only", "--format=", "HEAD"))
+		})
+	}
+}
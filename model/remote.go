@@ -0,0 +1,250 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"groundcontrol/gitutil"
+)
+
+// Names of the Git remotes that have a special meaning.
+const (
+	// OriginRemote is the remote of the Repository of a Project, usually a fork.
+	OriginRemote = "origin"
+	// UpstreamRemote is the remote a fork was created from.
+	UpstreamRemote = "upstream"
+)
+
+// HasUpstream returns whether one of the Remotes is named upstream.
+func (n *Project) HasUpstream() bool {
+	return n.upstreamURL() != ""
+}
+
+// upstreamURL returns the URL of the upstream remote.
+// It returns an empty string if there isn't one.
+func (n *Project) upstreamURL() string {
+	for _, remote := range n.Remotes {
+		if remote.Name == UpstreamRemote {
+			return remote.URL
+		}
+	}
+	return ""
+}
+
+// upstreamReferenceName returns the name of the local reference that points to the remote reference on upstream.
+func (n *Project) upstreamReferenceName() plumbing.ReferenceName {
+	return n.remoteReferenceName(UpstreamRemote, plumbing.ReferenceName(n.RemoteReference))
+}
+
+// syncRemotes creates the Remotes in the repository, or updates their URL and refspec if they changed.
+// The refspec of a single branch Project changes when another branch is checked out.
+// Remotes that were added manually are left alone.
+func (n *Project) syncRemotes(repo *git.Repository) error {
+	for _, remote := range n.Remotes {
+		refSpec := n.fetchRefSpec(remote.Name)
+		existing, err := repo.Remote(remote.Name)
+		if err == nil {
+			urls := existing.Config().URLs
+			fetch := existing.Config().Fetch
+			if len(urls) == 1 && urls[0] == remote.URL && len(fetch) == 1 && fetch[0] == refSpec {
+				continue
+			}
+			if err := repo.DeleteRemote(remote.Name); err != nil {
+				return err
+			}
+		} else if err != git.ErrRemoteNotFound {
+			return err
+		}
+		_, err = repo.CreateRemote(&config.RemoteConfig{
+			Name:  remote.Name,
+			URLs:  []string{remote.URL},
+			Fetch: []config.RefSpec{refSpec},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchUpstream syncs the Remotes of a cloned repository then fetches upstream if its remote reference moved.
+func (n *Project) fetchUpstream(ctx context.Context) error {
+	if !n.IsCloned(ctx) {
+		return nil
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	if err := n.syncRemotes(repo); err != nil {
+		return err
+	}
+	url := n.upstreamURL()
+	if url == "" {
+		return nil
+	}
+	remoteHash, err := n.listRemoteHash(ctx, url)
+	if err != nil || remoteHash.IsZero() {
+		return err
+	}
+	ref, err := repo.Reference(n.upstreamReferenceName(), true)
+	if err == nil && ref.Hash() == remoteHash {
+		return nil
+	}
+	auth, err := gitAuth(ctx, url)
+	if err != nil {
+		return err
+	}
+	opts := git.FetchOptions{RemoteName: UpstreamRemote, Depth: n.Depth, Auth: auth}
+	err = repo.FetchContext(ctx, &opts)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return gitError(url, err)
+	}
+	return nil
+}
+
+// syncUpstreamStatus syncs the status of the local branch compared to upstream.
+func (n *Project) syncUpstreamStatus(ctx context.Context) error {
+	n.IsBehindUpstream = false
+	n.IsAheadOfUpstream = false
+	n.UpstreamBehindCount = 0
+	n.UpstreamAheadCount = 0
+	if !n.HasUpstream() || !n.IsCloned(ctx) {
+		return nil
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	upstreamRef, err := repo.Reference(n.upstreamReferenceName(), true)
+	if err == plumbing.ErrReferenceNotFound {
+		// The branch doesn't exist upstream.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	localRef, err := repo.Reference(plumbing.ReferenceName(n.LocalReference), true)
+	if err != nil {
+		return err
+	}
+	n.UpstreamAheadCount, n.UpstreamBehindCount, err = gitutil.AheadBehind(
+		ctx,
+		repo,
+		localRef.Hash(),
+		upstreamRef.Hash(),
+	)
+	if err != nil {
+		return err
+	}
+	n.IsBehindUpstream = n.UpstreamBehindCount > 0
+	n.IsAheadOfUpstream = n.UpstreamAheadCount > 0
+	return nil
+}
+
+// SyncFromUpstream fast-forwards the local branch to the remote reference on upstream, pushes it to origin,
+// then syncs the Project.
+// It returns ErrNoUpstream if the Project doesn't have an upstream remote, and ErrUpstreamDiverged if either the
+// local branch or the remote reference on origin have Commits that aren't upstream.
+func (n *Project) SyncFromUpstream(ctx context.Context) error {
+	defer func() {
+		n.IsSyncingFromUpstream = false
+		n.MustStore(ctx)
+	}()
+	n.IsSyncingFromUpstream = true
+	n.MustStore(ctx)
+
+	if !n.HasUpstream() {
+		return ErrNoUpstream
+	}
+	if err := n.fetchUpstream(ctx); err != nil {
+		return err
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	upstreamRef, err := repo.Reference(n.upstreamReferenceName(), true)
+	if err != nil {
+		return err
+	}
+	localRefName := plumbing.ReferenceName(n.LocalReference)
+	localRef, err := repo.Reference(localRefName, true)
+	if err != nil {
+		return err
+	}
+	remoteRef, err := repo.Reference(n.localRemoteReferenceName(), true)
+	if err != nil && err != plumbing.ErrReferenceNotFound {
+		return err
+	}
+	if remoteRef != nil {
+		// Catch it before moving the local branch since the push would be rejected.
+		if ahead, _, err := gitutil.AheadBehind(ctx, repo, remoteRef.Hash(), upstreamRef.Hash()); err != nil {
+			return err
+		} else if ahead > 0 {
+			return ErrUpstreamDiverged
+		}
+	}
+	ahead, behind, err := gitutil.AheadBehind(ctx, repo, localRef.Hash(), upstreamRef.Hash())
+	if err != nil {
+		return err
+	}
+	if ahead > 0 {
+		return ErrUpstreamDiverged
+	}
+	if behind > 0 {
+		if err := n.fastForward(ctx, repo, localRefName, upstreamRef.Hash()); err != nil {
+			return err
+		}
+	}
+	if err := n.push(ctx, repo); err != nil {
+		return err
+	}
+	return n.Sync(ctx)
+}
+
+// fastForward moves a local branch to a descendant commit.
+// The working tree is updated if the branch is checked out, in which case it must be clean.
+func (n *Project) fastForward(
+	ctx context.Context,
+	repo *git.Repository,
+	refName plumbing.ReferenceName,
+	hash plumbing.Hash,
+) error {
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if head.Name() != refName {
+		return repo.Storer.SetReference(plumbing.NewHashReference(refName, hash))
+	}
+	isClean, err := n.checkIfClean(ctx)
+	if err != nil {
+		return err
+	}
+	if !isClean {
+		return ErrNotClean
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset})
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+)
+
+func TestProjectConfig_remotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		remotes map[string]string
+		want    []*Remote
+	}{{
+		"none",
+		nil,
+		[]*Remote{},
+	}, {
+		"origin",
+		map[string]string{"origin": "git@example.com:fork.git"},
+		[]*Remote{},
+	}, {
+		"sorted",
+		map[string]string{
+			"upstream": "git@example.com:upstream.git",
+			"origin":   "git@example.com:fork.git",
+			"backup":   "git@example.com:backup.git",
+		},
+		[]*Remote{
+			{Name: "backup", URL: "git@example.com:backup.git"},
+			{Name: "upstream", URL: "git@example.com:upstream.git"},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ProjectConfig{Remotes: tt.remotes}.remotes())
+		})
+	}
+}
+
+// newTestFork creates a fork of a new repository and a Project that tracks both.
+// It returns the context, the Project, and a working copy that can push to upstream.
+func newTestFork(t *testing.T, dir string) (context.Context, *Project, string) {
+	t.Helper()
+	upstream, work := newTestRemote(t, dir, "upstream")
+	fork := filepath.Join(dir, "fork.git")
+	testGit(t, dir, "clone", "-q", "--bare", upstream, fork)
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, fork)
+	project.Remotes = []*Remote{{Name: UpstreamRemote, URL: upstream}}
+	project.MustStore(ctx)
+	return ctx, project, work
+}
+
+func TestProject_SyncFromUpstream(t *testing.T) {
+	t.Run("fast-forward", func(t *testing.T) {
+		dir, clean := newTestDir(t)
+		defer clean()
+		ctx, project, work := newTestFork(t, dir)
+		require.NoError(t, project.Clone(ctx))
+		hash := testCommitFile(t, work, "a.txt", "a\n")
+		testGit(t, work, "push", "-q", "origin", "master")
+
+		require.NoError(t, project.Sync(ctx))
+		assert.Equal(t, 1, project.UpstreamBehindCount)
+
+		require.NoError(t, project.SyncFromUpstream(ctx))
+		assert.Equal(t, 0, project.UpstreamBehindCount)
+		assert.Equal(t, 0, project.BehindCount)
+		assert.Equal(t, hash, testGit(t, project.Path(ctx), "rev-parse", "HEAD"))
+		assert.Equal(t, hash, testGit(t, dir, "--git-dir", project.Repository, "rev-parse", "master"))
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		dir, clean := newTestDir(t)
+		defer clean()
+		ctx, project, work := newTestFork(t, dir)
+		require.NoError(t, project.Clone(ctx))
+		testCommitFile(t, work, "a.txt", "a\n")
+		testGit(t, work, "push", "-q", "origin", "master")
+		testCommitFile(t, project.Path(ctx), "b.txt", "b\n")
+
+		assert.Equal(t, ErrUpstreamDiverged, project.SyncFromUpstream(ctx))
+	})
+}
+
+func TestProject_Sync_unreachableUpstream(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	ctx, project, _ := newTestFork(t, dir)
+	project.Remotes[0].URL = filepath.Join(dir, "missing.git")
+	project.MustStore(ctx)
+
+	require.NoError(t, project.Clone(ctx))
+	require.NoError(t, project.Sync(ctx))
+	assert.NotNil(t, project.LastSyncedAt)
+}
+
+func TestProject_syncRemotes_singleBranch(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	ctx, project, _ := newTestFork(t, dir)
+	project.SingleBranch = true
+	project.MustStore(ctx)
+	require.NoError(t, project.Clone(ctx))
+	repo, err := git.PlainOpen(project.Path(ctx))
+	require.NoError(t, err)
+
+	project.RemoteReference = "refs/heads/feature"
+	require.NoError(t, project.syncRemotes(repo))
+
+	remote, err := repo.Remote(UpstreamRemote)
+	require.NoError(t, err)
+	assert.Equal(t, []config.RefSpec{"+refs/heads/feature:refs/remotes/upstream/feature"}, remote.Config().Fetch)
+}
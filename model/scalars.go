@@ -16,6 +16,7 @@ package model
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"io"
 	"strconv"
 	"time"
@@ -50,6 +51,16 @@ func (d DateTime) MarshalGQL(w io.Writer) {
 	_, _ = w.Write([]byte(strconv.Quote(time.Time(d).Format(DateFormat))))
 }
 
+// MarshalJSON implements the json.Marshaler interface.
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	return time.Time(d).MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *DateTime) UnmarshalJSON(data []byte) error {
+	return (*time.Time)(d).UnmarshalJSON(data)
+}
+
 // Hash holds a Git hash.
 type Hash []byte
 
@@ -76,3 +87,22 @@ func (h *Hash) UnmarshalGQL(v interface{}) error {
 func (h Hash) MarshalGQL(w io.Writer) {
 	_, _ = w.Write([]byte(strconv.Quote(hex.EncodeToString(h))))
 }
+
+// MarshalJSON implements the json.Marshaler interface.
+func (h Hash) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (h *Hash) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(str)
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
@@ -17,6 +17,9 @@ package model
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"groundcontrol/appcontext"
 )
 
 // String is a string representation for the type instance.
@@ -29,6 +32,45 @@ func (n *Service) LongString(ctx context.Context) string {
 	return fmt.Sprintf("%s » %s", n.Workspace(ctx), n)
 }
 
+// IsBusy returns whether the Service is starting, running, or stopping.
+func (n *Service) IsBusy() bool {
+	switch n.Status {
+	case ServiceStatusStarting, ServiceStatusRunning, ServiceStatusReady, ServiceStatusStopping:
+		return true
+	}
+	return false
+}
+
+// IsReady returns whether the Service can be used by the Services that need it.
+// A Service without a Healthcheck is ready as soon as it is running.
+func (n *Service) IsReady() bool {
+	if n.Healthcheck == nil {
+		return n.Status == ServiceStatusRunning
+	}
+	return n.Status == ServiceStatusReady
+}
+
+// DeleteServiceRecursive deletes a Service.
+// A running Service is asked to stop without waiting for it to exit, and
+// ErrBusy is returned so that it is deleted by a later sync. It also returns
+// ErrBusy if the Service is otherwise active, for instance if it is starting.
+func DeleteServiceRecursive(ctx context.Context, id string) error {
+	service, err := LoadService(ctx, id)
+	if err != nil {
+		return err
+	}
+	if service.Status == ServiceStatusRunning || service.Status == ServiceStatusReady {
+		if err := appcontext.Get(ctx).Services.Stop(ctx, id); err != nil {
+			return err
+		}
+		return ErrBusy
+	}
+	if service.IsBusy() {
+		return ErrBusy
+	}
+	return DeleteService(ctx, id)
+}
+
 // ComputeDependencies computes the dependencies of the Service based on the Services it needs.
 func (n *Service) ComputeDependencies(ctx context.Context) error {
 	deps, err := n.TopologicalSort(ctx)
@@ -109,3 +151,18 @@ func (n *Service) depSort(ctx context.Context, marks *map[string]bool) ([]*Servi
 	deps = append(deps, n)
 	return deps, nil
 }
+
+// BackoffDuration returns the Backoff as a duration.
+func (n *Service) BackoffDuration() time.Duration {
+	return time.Duration(n.Backoff) * time.Millisecond
+}
+
+// IntervalDuration returns the Interval as a duration.
+func (n *Healthcheck) IntervalDuration() time.Duration {
+	return time.Duration(n.Interval) * time.Millisecond
+}
+
+// TimeoutDuration returns the Timeout as a duration.
+func (n *Healthcheck) TimeoutDuration() time.Duration {
+	return time.Duration(n.Timeout) * time.Millisecond
+}
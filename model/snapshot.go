@@ -0,0 +1,245 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+// snapshotExt is the extension of the files storing Snapshots.
+const snapshotExt = ".json"
+
+// Snapshot saves the commit checked out by each cloned Project of the Workspace to disk and stores the Snapshot.
+// If the name is empty, the current time is used.
+func (n *Workspace) Snapshot(ctx context.Context, name string) (*Snapshot, error) {
+	now := time.Now()
+	if name == "" {
+		name = now.Format("2006-01-02T15-04-05")
+	}
+	filename := n.snapshotPath(ctx, name)
+	if _, err := os.Stat(filename); err == nil {
+		return nil, ErrSnapshotExists
+	}
+	snapshot := &Snapshot{
+		ID:          relay.EncodeID(NodeTypeSnapshot, n.Slug, name),
+		Name:        name,
+		CreatedAt:   DateTime(now),
+		WorkspaceID: n.ID,
+		Projects:    []*ProjectSnapshot{},
+	}
+	for _, projectID := range n.ProjectsIDs {
+		project := MustLoadProject(ctx, projectID)
+		if !project.IsCloned(ctx) {
+			continue
+		}
+		projectSnapshot, err := project.snapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Projects = append(snapshot.Projects, projectSnapshot)
+	}
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filename, bytes, 0644); err != nil {
+		return nil, err
+	}
+	snapshot.MustStore(ctx)
+	return snapshot, nil
+}
+
+// Snapshots lists the saved Snapshots of the Workspace from the most recent using Relay pagination.
+func (n *Workspace) Snapshots(ctx context.Context, after, before *string, first, last *int) (*SnapshotConnection, error) {
+	ids, err := n.loadSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return PaginateSnapshotIDSlice(ctx, ids, after, before, first, last, nil)
+}
+
+// snapshotPath returns the path to the file storing a Snapshot.
+// The name is escaped since it can contain any character.
+func (n *Workspace) snapshotPath(ctx context.Context, name string) string {
+	dir := appcontext.Get(ctx).GetSnapshotsPath(n.Slug)
+	return filepath.Join(dir, url.PathEscape(name)+snapshotExt)
+}
+
+// loadSnapshots loads the Snapshots of the Workspace from disk and stores them.
+// It returns their IDs from the most recent.
+func (n *Workspace) loadSnapshots(ctx context.Context) ([]string, error) {
+	appCtx := appcontext.Get(ctx)
+	dir := appCtx.GetSnapshotsPath(n.Slug)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*Snapshot
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), snapshotExt) {
+			continue
+		}
+		filename := filepath.Join(dir, file.Name())
+		snapshot, err := readSnapshot(filename)
+		if err != nil {
+			appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "%s  could not load snapshot %s because %s", n, filename, err.Error())
+			continue
+		}
+		// The file could have been copied from another Workspace.
+		snapshot.ID = relay.EncodeID(NodeTypeSnapshot, n.Slug, snapshot.Name)
+		snapshot.WorkspaceID = n.ID
+		snapshot.MustStore(ctx)
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return time.Time(snapshots[i].CreatedAt).After(time.Time(snapshots[j].CreatedAt))
+	})
+	ids := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		ids[i] = snapshot.ID
+	}
+	return ids, nil
+}
+
+// readSnapshot reads a Snapshot from a file.
+func readSnapshot(filename string) (*Snapshot, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(bytes, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindSnapshot loads a Snapshot, reading the Snapshots of its Workspace from disk if it isn't stored yet.
+func FindSnapshot(ctx context.Context, id string) (*Snapshot, error) {
+	snapshot, err := LoadSnapshot(ctx, id)
+	if err != ErrNotFound {
+		return snapshot, err
+	}
+	identifiers, err := relay.DecodeID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(identifiers) != 3 || identifiers[0] != NodeTypeSnapshot {
+		return nil, ErrType
+	}
+	viewer := MustLoadUser(ctx, appcontext.Get(ctx).ViewerID)
+	workspace := viewer.Workspace(ctx, identifiers[1])
+	if workspace == nil {
+		return nil, ErrNotFound
+	}
+	if _, err := workspace.loadSnapshots(ctx); err != nil {
+		return nil, err
+	}
+	return LoadSnapshot(ctx, id)
+}
+
+// snapshot returns the commit checked out by the Project.
+func (n *Project) snapshot(ctx context.Context) (*ProjectSnapshot, error) {
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	hash := head.Hash()
+	snapshot := &ProjectSnapshot{
+		ProjectSlug: n.Slug,
+		Hash:        Hash(hash[:]),
+	}
+	if head.Name().IsBranch() {
+		branch := head.Name().String()
+		snapshot.Branch = &branch
+	}
+	return snapshot, nil
+}
+
+// Restore checks out a commit then syncs the Project.
+// If the branch points to the commit, the branch is checked out, otherwise HEAD is detached.
+// The commit is fetched if it is missing.
+// It returns ErrNotClean if there are uncommitted changes.
+func (n *Project) Restore(ctx context.Context, hash Hash, branch *string) error {
+	defer func() {
+		n.IsCheckingOut = false
+		n.MustStore(ctx)
+	}()
+	n.IsCheckingOut = true
+	n.MustStore(ctx)
+
+	isClean, err := n.checkIfClean(ctx)
+	if err != nil {
+		return err
+	}
+	if !isClean {
+		return ErrNotClean
+	}
+	repo, err := n.openRepository(ctx)
+	if err != nil {
+		return err
+	}
+	var commitHash plumbing.Hash
+	copy(commitHash[:], hash)
+	_, err = repo.CommitObject(commitHash)
+	if err == plumbing.ErrObjectNotFound {
+		if err := n.fetch(ctx); err != nil {
+			return err
+		}
+		_, err = repo.CommitObject(commitHash)
+	}
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	opts := git.CheckoutOptions{Hash: commitHash}
+	if branch != nil {
+		ref, err := repo.Reference(plumbing.ReferenceName(*branch), true)
+		if err == nil && ref.Hash() == commitHash {
+			opts = git.CheckoutOptions{Branch: ref.Name()}
+		}
+	}
+	if err := worktree.Checkout(&opts); err != nil {
+		return err
+	}
+	return n.syncCheckedOut(ctx, repo)
+}
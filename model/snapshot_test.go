@@ -0,0 +1,96 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+func TestHash_JSON(t *testing.T) {
+	hash, err := hex.DecodeString("5f3c1a9e8b7d6c4f2a1e0d9c8b7a6f5e4d3c2b1a")
+	require.NoError(t, err)
+
+	bytes, err := json.Marshal(Hash(hash))
+	require.NoError(t, err)
+	assert.Equal(t, `"5f3c1a9e8b7d6c4f2a1e0d9c8b7a6f5e4d3c2b1a"`, string(bytes))
+
+	var got Hash
+	require.NoError(t, json.Unmarshal(bytes, &got))
+	assert.Equal(t, Hash(hash), got)
+}
+
+func TestWorkspace_Snapshot(t *testing.T) {
+	dir, clean := newTestDir(t)
+	defer clean()
+	repository, work := newTestRemote(t, dir, "remote")
+	ctx := newTestContext(dir)
+	project := newTestProject(ctx, repository)
+	require.NoError(t, project.Clone(ctx))
+	workspace := project.Workspace(ctx)
+
+	// FindSnapshot finds Workspaces through the Sources of the viewer.
+	appCtx := appcontext.Get(ctx)
+	appCtx.ViewerID = relay.EncodeID(NodeTypeUser, "viewer")
+	source := &DirectorySource{
+		ID:            relay.EncodeID(NodeTypeDirectorySource, dir),
+		UserID:        appCtx.ViewerID,
+		WorkspacesIDs: []string{workspace.ID},
+	}
+	source.MustStore(ctx)
+	(&User{ID: appCtx.ViewerID, SourcesIDs: []string{source.ID}}).MustStore(ctx)
+
+	snapshot, err := workspace.Snapshot(ctx, "before")
+	require.NoError(t, err)
+	_, err = workspace.Snapshot(ctx, "before")
+	assert.Equal(t, ErrSnapshotExists, err)
+
+	// Read the snapshot from disk like after a restart.
+	appCtx.Nodes.Delete(snapshot.ID)
+	found, err := FindSnapshot(ctx, snapshot.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Projects, 1)
+	assert.Equal(t, snapshot.Projects[0].Hash, found.Projects[0].Hash)
+	assert.Equal(t, "refs/heads/master", *found.Projects[0].Branch)
+
+	hash := testCommitFile(t, work, "a.txt", "a\n")
+	testGit(t, work, "push", "-q", "origin", "master")
+	require.NoError(t, project.Pull(ctx))
+	assert.Equal(t, hash, testGit(t, project.Path(ctx), "rev-parse", "HEAD"))
+
+	t.Run("dirty", func(t *testing.T) {
+		filename := filepath.Join(project.Path(ctx), "dirty.txt")
+		require.NoError(t, ioutil.WriteFile(filename, []byte("dirty\n"), 0644))
+		defer testGit(t, project.Path(ctx), "clean", "-fq")
+		assert.Equal(t, ErrNotClean, project.Restore(ctx, found.Projects[0].Hash, found.Projects[0].Branch))
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		require.NoError(t, project.Restore(ctx, found.Projects[0].Hash, found.Projects[0].Branch))
+		assert.Equal(t, hex.EncodeToString(found.Projects[0].Hash), testGit(t, project.Path(ctx), "rev-parse", "HEAD"))
+		assert.Equal(t, "HEAD", project.LocalReference)
+		assert.Equal(t, 1, project.BehindCount, "the restored commit is behind the remote branch")
+		assert.Equal(t, 0, project.AheadCount)
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"groundcontrol/appcontext"
 	"groundcontrol/relay"
 	"groundcontrol/store"
 )
@@ -30,6 +31,10 @@ type Source interface {
 	User(context.Context) *User
 	// GetWorkspacesIDs returns the IDs of the workspaces.
 	GetWorkspacesIDs() []string
+	// GetPriority returns the priority used to resolve conflicts.
+	GetPriority() int
+	// GetConflicts returns the Workspace slugs that are also defined by other Sources.
+	GetConflicts() []*SourceConflict
 	// Workspaces are the workspaces using Relay pagination.
 	Workspaces(ctx context.Context, after, before *string, first, last *int) (*WorkspaceConnection, error)
 }
@@ -45,6 +50,8 @@ func LoadSource(ctx context.Context, id string) (Source, error) {
 		return LoadDirectorySource(ctx, id)
 	case NodeTypeGitSource:
 		return LoadGitSource(ctx, id)
+	case NodeTypeHTTPSource:
+		return LoadHTTPSource(ctx, id)
 	}
 	return nil, ErrType
 }
@@ -58,10 +65,47 @@ func MustLoadSource(ctx context.Context, id string) Source {
 	return node
 }
 
+// deleteOrphanedWorkspaces deletes Workspaces that were removed from a Source along with their child Nodes.
+// Workspaces that now belong to another Source are left untouched.
+// It returns the IDs of the Workspaces that couldn't be deleted yet because they are busy.
+func deleteOrphanedWorkspaces(ctx context.Context, sourceID string, workspacesIDs []string) []string {
+	appCtx := appcontext.Get(ctx)
+	log := appCtx.Log
+	var keptIDs []string
+	for _, workspaceID := range workspacesIDs {
+		workspace, err := LoadWorkspace(ctx, workspaceID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			log.ErrorWithOwner(ctx, sourceID, "could not delete workspace because %s", err.Error())
+			continue
+		}
+		if workspace.SourceID != sourceID {
+			continue
+		}
+		err = DeleteWorkspaceRecursive(ctx, workspaceID)
+		if err == ErrBusy {
+			logDeletionPostponed(ctx, workspace.String())
+			keptIDs = append(keptIDs, workspaceID)
+			continue
+		}
+		if err != nil {
+			log.ErrorWithOwner(ctx, sourceID, "could not delete workspace %s because %s", workspace, err.Error())
+			keptIDs = append(keptIDs, workspaceID)
+		}
+	}
+	return keptIDs
+}
+
 // SyncWorkspacesInDirectory syncs the Workspaces in a directory recursively.
-func SyncWorkspacesInDirectory(ctx context.Context, directory, sourceID string) ([]string, error) {
-	var workspaceIDs []string
-	return workspaceIDs, filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+// It returns the IDs of the Workspaces stored and the conflicts with other Sources.
+func SyncWorkspacesInDirectory(ctx context.Context, directory string, source Source) ([]string, []*SourceConflict, error) {
+	var (
+		workspaceIDs []string
+		conflicts    []*SourceConflict
+	)
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -80,11 +124,13 @@ func SyncWorkspacesInDirectory(ctx context.Context, directory, sourceID string)
 		if err != nil {
 			return err
 		}
-		ids, err := config.storeNodes(ctx, sourceID)
+		ids, found, err := config.storeNodes(ctx, source)
 		if err != nil {
 			return err
 		}
 		workspaceIDs = append(workspaceIDs, ids...)
+		conflicts = append(conflicts, found...)
 		return nil
 	})
+	return workspaceIDs, conflicts, err
 }
@@ -0,0 +1,136 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"strings"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+// Source is the other Source that defines the Workspace.
+func (n *SourceConflict) Source(ctx context.Context) Source {
+	return MustLoadSource(ctx, n.SourceID)
+}
+
+// findSlugConflicts returns a conflict for every other Source of the viewer
+// that defines a Workspace with the given slug.
+func findSlugConflicts(ctx context.Context, source Source, workspaceID, slug string) []*SourceConflict {
+	appCtx := appcontext.Get(ctx)
+	viewer, err := LoadUser(ctx, appCtx.ViewerID)
+	if err != nil {
+		return nil
+	}
+	var conflicts []*SourceConflict
+	for _, otherID := range viewer.SourcesIDs {
+		if otherID == source.GetID() {
+			continue
+		}
+		other, err := LoadSource(ctx, otherID)
+		if err != nil || !sourceDefinesWorkspace(other, workspaceID, slug) {
+			continue
+		}
+		conflicts = append(conflicts, &SourceConflict{
+			Slug:          slug,
+			SourceID:      otherID,
+			HasPrecedence: sourcePrecedes(source, other),
+		})
+	}
+	return conflicts
+}
+
+// sourceDefinesWorkspace returns whether a Source defines a Workspace,
+// including when it lost a conflict for it.
+func sourceDefinesWorkspace(source Source, workspaceID, slug string) bool {
+	for _, id := range source.GetWorkspacesIDs() {
+		if id == workspaceID {
+			return true
+		}
+	}
+	for _, conflict := range source.GetConflicts() {
+		if conflict.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcePrecedes returns whether the first Source takes precedence over the
+// second one. The Source with the highest priority wins, and the first one in
+// alphabetical order wins when they have the same priority.
+func sourcePrecedes(a, b Source) bool {
+	if a.GetPriority() != b.GetPriority() {
+		return a.GetPriority() > b.GetPriority()
+	}
+	u, v := strings.ToLower(sourceSortKey(a)), strings.ToLower(sourceSortKey(b))
+	if u != v {
+		return u < v
+	}
+	return a.GetID() < b.GetID()
+}
+
+// hasPrecedence returns whether a Source wins all the given conflicts.
+func hasPrecedence(conflicts []*SourceConflict) bool {
+	for _, conflict := range conflicts {
+		if !conflict.HasPrecedence {
+			return false
+		}
+	}
+	return true
+}
+
+// conflictsWorkspacesIDs returns the IDs of the Workspaces of conflicts.
+func conflictsWorkspacesIDs(conflicts []*SourceConflict) []string {
+	var ids []string
+	for _, conflict := range conflicts {
+		ids = append(ids, relay.EncodeID(NodeTypeWorkspace, conflict.Slug))
+	}
+	return ids
+}
+
+// logNewConflicts logs the conflicts that weren't already known.
+func logNewConflicts(ctx context.Context, source Source, was, conflicts []*SourceConflict) {
+	log := appcontext.Get(ctx).Log
+	for _, conflict := range conflicts {
+		if containsConflict(was, conflict) {
+			continue
+		}
+		var winner, loser Source = source, conflict.Source(ctx)
+		if !conflict.HasPrecedence {
+			winner, loser = loser, winner
+		}
+		log.ErrorWithOwner(
+			ctx,
+			source.GetID(),
+			"workspace %s is defined by %s and %s, using the one from %s",
+			conflict.Slug,
+			winner,
+			loser,
+			winner,
+		)
+	}
+}
+
+// containsConflict returns whether a slice contains a conflict.
+func containsConflict(conflicts []*SourceConflict, conflict *SourceConflict) bool {
+	for _, v := range conflicts {
+		if *v == *conflict {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourcePrecedes(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Source
+		b    Source
+		want bool
+	}{{
+		"higher priority",
+		&DirectorySource{ID: "a", Directory: "/b", Priority: 1},
+		&DirectorySource{ID: "b", Directory: "/a"},
+		true,
+	}, {
+		"lower priority",
+		&DirectorySource{ID: "a", Directory: "/a", Priority: -1},
+		&GitSource{ID: "b", Repository: "git@github.com:b/b.git"},
+		false,
+	}, {
+		"same priority first alphabetically",
+		&GitSource{ID: "a", Repository: "git@github.com:A/a.git"},
+		&HTTPSource{ID: "b", URLs: []string{"https://b.com/ws.yml"}},
+		true,
+	}, {
+		"same priority last alphabetically",
+		&HTTPSource{ID: "a", URLs: []string{"https://b.com/ws.yml"}},
+		&GitSource{ID: "b", Repository: "git@github.com:a/a.git"},
+		false,
+	}, {
+		"same priority and key",
+		&GitSource{ID: "a", Repository: "git@github.com:a/a.git", Reference: "refs/heads/master"},
+		&GitSource{ID: "b", Repository: "git@github.com:a/a.git", Reference: "refs/heads/develop"},
+		true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sourcePrecedes(tt.a, tt.b))
+			assert.Equal(t, !tt.want, sourcePrecedes(tt.b, tt.a))
+		})
+	}
+}
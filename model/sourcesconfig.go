@@ -16,10 +16,12 @@ package model
 
 import (
 	"context"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	yaml "gopkg.in/yaml.v2"
 
 	"groundcontrol/appcontext"
@@ -31,19 +33,31 @@ type SourcesConfig struct {
 	Filename         string                  `json:"-" yaml:"-"`
 	DirectorySources []DirectorySourceConfig `json:"directorySources" yaml:"directory-sources"`
 	GitSources       []GitSourceConfig       `json:"gitSources" yaml:"git-sources"`
+	HTTPSources      []HTTPSourceConfig      `json:"httpSources" yaml:"http-sources"`
 }
 
 // DirectorySourceConfig contains all the data in a YAML directory source config file.
 type DirectorySourceConfig struct {
 	Directory string `json:"directory"`
+	Priority  int    `json:"priority" yaml:"priority,omitempty"`
 	ID        string `json:"-" yaml:"-"`
 }
 
 // GitSourceConfig contains all the data in a YAML Git source config file.
 type GitSourceConfig struct {
-	Repository string `json:"repository"`
-	Reference  string `json:"reference"`
-	ID         string `json:"-" yaml:"-"`
+	Repository     string `json:"repository"`
+	Reference      string `json:"reference"`
+	Priority       int    `json:"priority" yaml:"priority,omitempty"`
+	Commit         string `json:"commit" yaml:"commit,omitempty"`
+	ApprovedCommit string `json:"approvedCommit" yaml:"approved-commit,omitempty"`
+	ID             string `json:"-" yaml:"-"`
+}
+
+// HTTPSourceConfig contains all the data in a YAML HTTP source config file.
+type HTTPSourceConfig struct {
+	URLs     []string `json:"urls"`
+	Priority int      `json:"priority" yaml:"priority,omitempty"`
+	ID       string   `json:"-" yaml:"-"`
 }
 
 // Store stores nodes for the content of the sources config.
@@ -58,6 +72,7 @@ func (c *SourcesConfig) Store(ctx context.Context) error {
 				ID:        relay.EncodeID(NodeTypeDirectorySource, sourceConfig.Directory),
 				UserID:    appCtx.ViewerID,
 				Directory: sourceConfig.Directory,
+				Priority:  sourceConfig.Priority,
 			}
 
 			c.DirectorySources[i].ID = source.ID
@@ -75,6 +90,23 @@ func (c *SourcesConfig) Store(ctx context.Context) error {
 				UserID:     appCtx.ViewerID,
 				Repository: sourceConfig.Repository,
 				Reference:  sourceConfig.Reference,
+				Priority:   sourceConfig.Priority,
+			}
+
+			if sourceConfig.Commit != "" {
+				hash, err := parseCommitHash(sourceConfig.Commit)
+				if err != nil {
+					return err
+				}
+				source.PinnedCommit = hash
+			}
+
+			if sourceConfig.ApprovedCommit != "" {
+				hash, err := parseCommitHash(sourceConfig.ApprovedCommit)
+				if err != nil {
+					return err
+				}
+				source.ApprovedCommit = hash
 			}
 
 			c.GitSources[i].ID = source.ID
@@ -82,6 +114,23 @@ func (c *SourcesConfig) Store(ctx context.Context) error {
 			source.MustStore(ctx)
 		}
 
+		for i, sourceConfig := range c.HTTPSources {
+			if len(sourceConfig.URLs) < 1 {
+				return ErrNoURLs
+			}
+
+			source := HTTPSource{
+				ID:       relay.EncodeID(append([]string{NodeTypeHTTPSource}, sourceConfig.URLs...)...),
+				UserID:   appCtx.ViewerID,
+				URLs:     sourceConfig.URLs,
+				Priority: sourceConfig.Priority,
+			}
+
+			c.HTTPSources[i].ID = source.ID
+			sourcesIDs = append(sourcesIDs, source.ID)
+			source.MustStore(ctx)
+		}
+
 		viewer.SourcesIDs = sourcesIDs
 		viewer.MustStore(ctx)
 
@@ -161,6 +210,54 @@ func (c *SourcesConfig) SetGitSource(ctx context.Context, repository, reference
 	return source.ID
 }
 
+// SetHTTPSource sets an HTTP source and stores the corresponding node.
+// It returns the ID of the source.
+func (c *SourcesConfig) SetHTTPSource(ctx context.Context, urls []string) string {
+	appCtx := appcontext.Get(ctx)
+
+	source := HTTPSource{
+		ID:     relay.EncodeID(append([]string{NodeTypeHTTPSource}, urls...)...),
+		UserID: appCtx.ViewerID,
+		URLs:   urls,
+	}
+
+	MustLockUser(ctx, appCtx.ViewerID, func(viewer *User) {
+		for _, sourceID := range viewer.SourcesIDs {
+			if sourceID == source.ID {
+				return
+			}
+		}
+
+		source.MustStore(ctx)
+
+		viewer.SourcesIDs = append(viewer.SourcesIDs, source.ID)
+		viewer.MustStore(ctx)
+
+		c.HTTPSources = append(
+			c.HTTPSources,
+			HTTPSourceConfig{
+				URLs: urls,
+				ID:   source.ID,
+			},
+		)
+	})
+
+	return source.ID
+}
+
+// ApproveGitSource sets the approved commit of a Git source.
+func (c *SourcesConfig) ApproveGitSource(ctx context.Context, id, commit string) {
+	appCtx := appcontext.Get(ctx)
+
+	MustLockUser(ctx, appCtx.ViewerID, func(*User) {
+		for i, v := range c.GitSources {
+			if v.ID == id {
+				c.GitSources[i].ApprovedCommit = commit
+			}
+		}
+	})
+}
+
 // Delete deletes a source.
 func (c *SourcesConfig) Delete(ctx context.Context, id string) error {
 	appCtx := appcontext.Get(ctx)
@@ -204,6 +301,17 @@ func (c *SourcesConfig) Delete(ctx context.Context, id string) error {
 					break
 				}
 			}
+		case NodeTypeHTTPSource:
+			// We can't delete the actual node because other node might reference it.
+			for i, v := range c.HTTPSources {
+				if v.ID == id {
+					c.HTTPSources = append(
+						c.HTTPSources[:i],
+						c.HTTPSources[i+1:]...,
+					)
+					break
+				}
+			}
 		default:
 			return ErrType
 		}
@@ -228,6 +336,19 @@ func (c *SourcesConfig) Save() error {
 	return ioutil.WriteFile(c.Filename, bytes, 0644)
 }
 
+// parseCommitHash parses a full Git commit hash using hexadecimal encoding.
+func parseCommitHash(str string) (*Hash, error) {
+	bytes, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) != len(plumbing.ZeroHash) {
+		return nil, ErrCommitHash
+	}
+	hash := Hash(bytes)
+	return &hash, nil
+}
+
 // LoadSourcesConfigYAML loads a source config from a YAML file.
 // It will create a file if it doesn't exist.
 func LoadSourcesConfigYAML(filename string) (*SourcesConfig, error) {
@@ -0,0 +1,34 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+func TestSourcesConfig_Store_noURLs(t *testing.T) {
+	ctx := newTestContext("")
+	appCtx := appcontext.Get(ctx)
+	appCtx.ViewerID = relay.EncodeID(NodeTypeUser, "viewer")
+	(&User{ID: appCtx.ViewerID}).MustStore(ctx)
+
+	config := SourcesConfig{HTTPSources: []HTTPSourceConfig{{}}}
+	assert.Equal(t, ErrNoURLs, config.Store(ctx))
+}
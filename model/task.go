@@ -18,8 +18,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"groundcontrol/appcontext"
+	"groundcontrol/shell"
 	"groundcontrol/util"
 )
 
@@ -33,10 +36,116 @@ func (n *Task) LongString(ctx context.Context) string {
 	return fmt.Sprintf("%s » %s", n.Workspace(ctx), n)
 }
 
+// IsBusy returns whether the Task is queued or running.
+func (n *Task) IsBusy() bool {
+	return n.Status == TaskStatusQueued || n.Status == TaskStatusRunning
+}
+
+// DeleteTaskRecursive deletes a Task along with its Steps, Commands, and TaskRuns.
+// It returns ErrBusy if the Task is queued or running.
+func DeleteTaskRecursive(ctx context.Context, id string) error {
+	// Check before locking since a running Task holds the lock until it exits.
+	task, err := LoadTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if task.IsBusy() {
+		return ErrBusy
+	}
+	return LockTaskE(ctx, id, func(task *Task) error {
+		if task.IsBusy() {
+			return ErrBusy
+		}
+		for _, stepID := range task.StepsIDs {
+			if err := DeleteStepRecursive(ctx, stepID); err != nil {
+				return err
+			}
+		}
+		for _, runID := range task.RunsIDs {
+			if err := DeleteTaskRun(ctx, runID); err != nil {
+				return err
+			}
+		}
+		if err := task.deleteOrphans(ctx, nil, nil); err != nil {
+			return err
+		}
+		return DeleteTask(ctx, id)
+	})
+}
+
+// DeleteStepRecursive deletes a Step along with its Commands.
+func DeleteStepRecursive(ctx context.Context, id string) error {
+	step, err := LoadStep(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, commandID := range step.CommandsIDs {
+		if err := DeleteCommand(ctx, commandID); err != nil {
+			return err
+		}
+	}
+	return DeleteStep(ctx, id)
+}
+
+// taskOrphans contains the IDs of the Steps and Commands removed from a Task.
+type taskOrphans struct {
+	stepsIDs    []string
+	commandsIDs []string
+}
+
+// tasksOrphans holds the orphans of the Tasks that were busy when their
+// Steps and Commands were removed, indexed by Task ID. They are only accessed
+// while the Task is locked.
+var tasksOrphans sync.Map
+
+// postponeOrphansDeletion remembers Steps and Commands removed from the Task
+// while it is busy so that they can be deleted once it is idle.
+func (n *Task) postponeOrphansDeletion(stepsIDs, commandsIDs []string) {
+	orphans := taskOrphans{}
+	if actual, ok := tasksOrphans.Load(n.ID); ok {
+		orphans = actual.(taskOrphans)
+	}
+	orphans.stepsIDs = append(orphans.stepsIDs, stepsIDs...)
+	orphans.commandsIDs = append(orphans.commandsIDs, commandsIDs...)
+	tasksOrphans.Store(n.ID, orphans)
+}
+
+// deleteOrphans deletes Steps and Commands removed from the Task, including
+// the ones whose deletion was postponed, unless the Task uses them again.
+func (n *Task) deleteOrphans(ctx context.Context, stepsIDs, commandsIDs []string) error {
+	if actual, ok := tasksOrphans.Load(n.ID); ok {
+		orphans := actual.(taskOrphans)
+		stepsIDs = append(stepsIDs, orphans.stepsIDs...)
+		commandsIDs = append(commandsIDs, orphans.commandsIDs...)
+		tasksOrphans.Delete(n.ID)
+	}
+	for _, stepID := range subtractIDs(stepsIDs, n.StepsIDs) {
+		if err := DeleteStep(ctx, stepID); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	for _, commandID := range subtractIDs(commandsIDs, n.commandsIDs(ctx)) {
+		if err := DeleteCommand(ctx, commandID); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandsIDs returns the IDs of the Commands of all the Steps.
+func (n *Task) commandsIDs(ctx context.Context) []string {
+	var ids []string
+	for _, stepID := range n.StepsIDs {
+		ids = append(ids, MustLoadStep(ctx, stepID).CommandsIDs...)
+	}
+	return ids
+}
+
 // Run executes the commands in the task.
 // Env is the environment of the Task. Each entry is of the form 'key=value'.
 func (n *Task) Run(ctx context.Context, env []string) error {
 	var err error
+	run := n.startRun(ctx, env)
 	defer func() {
 		if err == nil {
 			n.Status = TaskStatusStopped
@@ -44,39 +153,123 @@ func (n *Task) Run(ctx context.Context, env []string) error {
 			n.Status = TaskStatusFailed
 		}
 		n.MustStore(ctx)
+		n.endRun(ctx, run, err)
+		if err := n.deleteOrphans(ctx, nil, nil); err != nil {
+			appCtx := appcontext.Get(ctx)
+			appCtx.Log.WarningWithOwner(ctx, n.ID, "could not delete removed steps because %s", err.Error())
+		}
 	}()
 	n.Status = TaskStatusRunning
 	n.MustStore(ctx)
-	for _, stepID := range n.StepsIDs {
+	for index, stepID := range n.StepsIDs {
 		step := MustLoadStep(ctx, stepID)
 		n.CurrentStepID = stepID
-		if err = n.runStep(ctx, step, env); err != nil {
+		if err = n.runStep(ctx, run, index, step, env); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (n *Task) runStep(ctx context.Context, step *Step, env []string) error {
+func (n *Task) runStep(ctx context.Context, run *TaskRun, index int, step *Step, env []string) error {
+	mu := sync.Mutex{}
 	if len(step.ProjectsIDs) < 1 {
-		n.CurrentProjectID = ""
-		return n.runStepCmds(ctx, step, "", env)
+		n.CurrentProjectsIDs = nil
+		return n.runStepCmds(ctx, newStepRun(run, index, nil), step, "", "", env, &mu)
 	}
+	if step.Concurrency == 1 {
+		for _, projectID := range step.ProjectsIDs {
+			if err := n.runStepOnProject(ctx, run, index, step, projectID, false, env, &mu); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return n.runStepParallel(ctx, run, index, step, env, &mu)
+}
+
+// runStepParallel executes the commands of a Step on multiple Projects at once.
+// The first error cancels the commands running on other Projects.
+func (n *Task) runStepParallel(ctx context.Context, run *TaskRun, index int, step *Step, env []string, mu *sync.Mutex) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Each Project runs its own commands, so there is no single current Command.
+	mu.Lock()
+	n.CurrentCommandID = ""
+	n.MustStore(ctx)
+	mu.Unlock()
+	concurrency := step.Concurrency
+	if concurrency < 1 || concurrency > len(step.ProjectsIDs) {
+		concurrency = len(step.ProjectsIDs)
+	}
+	sem := make(chan struct{}, concurrency)
+	waitGroup := sync.WaitGroup{}
+	var stepErr error
 	for _, projectID := range step.ProjectsIDs {
-		project := MustLoadProject(ctx, projectID)
-		if err := project.EnsureCloned(ctx); err != nil {
-			return err
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
 		}
-		n.CurrentProjectID = projectID
-		if err := n.runStepCmds(ctx, step, project.Path(ctx), env); err != nil {
-			return err
+		if ctx.Err() != nil {
+			break
 		}
+		waitGroup.Add(1)
+		go func(projectID string) {
+			defer func() {
+				<-sem
+				waitGroup.Done()
+			}()
+			err := n.runStepOnProject(ctx, run, index, step, projectID, true, env, mu)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && stepErr == nil {
+				stepErr = err
+				cancel()
+			}
+		}(projectID)
 	}
-	return nil
+	waitGroup.Wait()
+	if stepErr == nil {
+		stepErr = ctx.Err()
+	}
+	return stepErr
 }
 
-func (n *Task) runStepCmds(ctx context.Context, step *Step, dir string, env []string) error {
-	runner, close, err := n.createRunner(ctx, dir, env)
+// runStepOnProject executes the commands of a Step on a Project.
+// If prefixed is true, the slug of the Project is added to the output of the commands.
+func (n *Task) runStepOnProject(ctx context.Context, run *TaskRun, index int, step *Step, projectID string, prefixed bool, env []string, mu *sync.Mutex) error {
+	project := MustLoadProject(ctx, projectID)
+	mu.Lock()
+	stepRun := newStepRun(run, index, project)
+	mu.Unlock()
+	if err := project.EnsureCloned(ctx); err != nil {
+		stepRun.Status = TaskStatusFailed
+		return err
+	}
+	prefix := ""
+	if prefixed {
+		prefix = project.Slug
+	}
+	mu.Lock()
+	n.CurrentProjectsIDs = append(n.CurrentProjectsIDs, projectID)
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		n.CurrentProjectsIDs = subtractIDs(n.CurrentProjectsIDs, []string{projectID})
+		n.MustStore(ctx)
+		mu.Unlock()
+	}()
+	return n.runStepCmds(ctx, stepRun, step, project.Path(ctx), prefix, env, mu)
+}
+
+func (n *Task) runStepCmds(ctx context.Context, stepRun *StepRun, step *Step, dir, prefix string, env []string, mu *sync.Mutex) (err error) {
+	defer func() {
+		stepRun.Status = TaskStatusStopped
+		if err != nil {
+			stepRun.Status = TaskStatusFailed
+		}
+	}()
+	runner, close, err := n.createRunner(ctx, dir, prefix, env)
 	if err != nil {
 		return err
 	}
@@ -84,21 +277,34 @@ func (n *Task) runStepCmds(ctx context.Context, step *Step, dir string, env []st
 	log := appcontext.Get(ctx).Log
 	for _, commandID := range step.CommandsIDs {
 		command := MustLoadCommand(ctx, commandID)
-		n.CurrentCommandID = commandID
-		n.MustStore(ctx)
-		log.InfoWithOwner(ctx, n.ID, command.Command)
-		if err := runner.Run(ctx, command.Command); err != nil {
+		// Commands are only prefixed when the Step runs on Projects in parallel.
+		if prefix == "" {
+			mu.Lock()
+			n.CurrentCommandID = commandID
+			n.MustStore(ctx)
+			mu.Unlock()
+		}
+		log.InfoWithOwner(ctx, n.ID, prefixLine(prefix, command.Command))
+		startedAt := time.Now()
+		err := runner.Run(ctx, command.Command)
+		stepRun.Commands = append(stepRun.Commands, &CommandRun{
+			Command:   command.Command,
+			ExitCode:  shell.ExitCode(err),
+			StartedAt: DateTime(startedAt),
+			Duration:  int(time.Since(startedAt) / time.Millisecond),
+		})
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (n *Task) createRunner(ctx context.Context, dir string, env []string) (appcontext.Runner, func(), error) {
+func (n *Task) createRunner(ctx context.Context, dir, prefix string, env []string) (appcontext.Runner, func(), error) {
 	appCtx := appcontext.Get(ctx)
 	log := appCtx.Log
-	stdout := util.LineSplitter(ctx, log.InfoWithOwner, n.ID)
-	stderr := util.LineSplitter(ctx, log.WarningWithOwner, n.ID)
+	stdout := util.LineSplitter(ctx, prefixLineWriter(log.InfoWithOwner, prefix), n.ID)
+	stderr := util.LineSplitter(ctx, prefixLineWriter(log.WarningWithOwner, prefix), n.ID)
 	close := func() {
 		stdout.Close()
 		stderr.Close()
@@ -111,3 +317,20 @@ func (n *Task) createRunner(ctx context.Context, dir string, env []string) (appc
 	}
 	return runner, close, nil
 }
+
+// prefixLineWriter returns a LineWriter that adds a prefix to the lines, if any.
+func prefixLineWriter(write util.LineWriter, prefix string) util.LineWriter {
+	if prefix == "" {
+		return write
+	}
+	return func(ctx context.Context, ownerID, msg string, a ...interface{}) string {
+		return write(ctx, ownerID, prefixLine(prefix, msg), a...)
+	}
+}
+
+func prefixLine(prefix, line string) string {
+	if prefix == "" {
+		return line
+	}
+	return prefix + "  " + line
+}
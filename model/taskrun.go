@@ -0,0 +1,155 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/relay"
+)
+
+// MaxTaskRuns is the maximum number of TaskRuns kept for each Task.
+const MaxTaskRuns = 20
+
+// startRun creates a TaskRun for a Task that is starting and adds it to its Runs.
+// The oldest TaskRuns are deleted if there are more than MaxTaskRuns.
+func (n *Task) startRun(ctx context.Context, env []string) *TaskRun {
+	now := time.Now()
+	run := &TaskRun{
+		ID:        relay.EncodeID(NodeTypeTaskRun, n.ID, fmt.Sprint(now.UnixNano())),
+		TaskID:    n.ID,
+		JobID:     appcontext.JobID(ctx),
+		Status:    TaskStatusRunning,
+		StartedAt: DateTime(now),
+		Variables: n.variableValues(ctx, env),
+	}
+	run.MustStore(ctx)
+	n.RunsIDs = append([]string{run.ID}, n.RunsIDs...)
+	for len(n.RunsIDs) > MaxTaskRuns {
+		last := len(n.RunsIDs) - 1
+		if err := DeleteTaskRun(ctx, n.RunsIDs[last]); err != nil && err != ErrNotFound {
+			panic(err)
+		}
+		n.RunsIDs = n.RunsIDs[:last]
+	}
+	return run
+}
+
+// endRun stores the final state of a TaskRun and saves the TaskRuns of the Task to disk.
+func (n *Task) endRun(ctx context.Context, run *TaskRun, err error) {
+	now := time.Now()
+	endedAt := DateTime(now)
+	duration := int(now.Sub(time.Time(run.StartedAt)) / time.Millisecond)
+	run.EndedAt = &endedAt
+	run.Duration = &duration
+	run.Status = TaskStatusStopped
+	if err != nil {
+		run.Status = TaskStatusFailed
+	}
+	run.MustStore(ctx)
+	if err := n.saveRuns(ctx); err != nil {
+		appCtx := appcontext.Get(ctx)
+		appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "%s  could not save task runs because %s", n.LongString(ctx), err.Error())
+	}
+}
+
+// newStepRun creates the result of a Step on a Project and adds it to a TaskRun.
+// The project can be nil if the Step has no Projects.
+func newStepRun(run *TaskRun, index int, project *Project) *StepRun {
+	stepRun := &StepRun{
+		Index:  index,
+		Status: TaskStatusRunning,
+	}
+	if project != nil {
+		stepRun.ProjectSlug = &project.Slug
+	}
+	run.Steps = append(run.Steps, stepRun)
+	return stepRun
+}
+
+// variableValues returns the values of the Variables of the Task in the environment.
+func (n *Task) variableValues(ctx context.Context, env []string) []*VariableValue {
+	values := []*VariableValue{}
+	for _, variableID := range n.VariablesIDs {
+		variable := MustLoadVariable(ctx, variableID)
+		prefix := variable.Name + "="
+		// Later entries take precedence, like in a shell.
+		for i := len(env) - 1; i >= 0; i-- {
+			if strings.HasPrefix(env[i], prefix) {
+				values = append(values, &VariableValue{
+					Name:  variable.Name,
+					Value: strings.TrimPrefix(env[i], prefix),
+				})
+				break
+			}
+		}
+	}
+	return values
+}
+
+// saveRuns saves the TaskRuns of the Task to disk, overwriting the file if it exists.
+func (n *Task) saveRuns(ctx context.Context) error {
+	runs := []*TaskRun{}
+	for _, runID := range n.RunsIDs {
+		runs = append(runs, MustLoadTaskRun(ctx, runID))
+	}
+	bytes, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+	filename := appcontext.Get(ctx).GetTaskRunsPath(n.Workspace(ctx).Slug, n.Name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	// Variables can contain secrets.
+	return ioutil.WriteFile(filename, bytes, 0600)
+}
+
+// loadRuns loads the TaskRuns of the Task from disk and stores them.
+// Runs that were interrupted are marked as failed.
+func (n *Task) loadRuns(ctx context.Context, workspaceSlug string) error {
+	filename := appcontext.Get(ctx).GetTaskRunsPath(workspaceSlug, n.Name)
+	bytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var runs []*TaskRun
+	if err := json.Unmarshal(bytes, &runs); err != nil {
+		return err
+	}
+	n.RunsIDs = nil
+	for _, run := range runs {
+		// Jobs don't survive restarts, and their IDs could be reused.
+		run.JobID = ""
+		run.TaskID = n.ID
+		if run.Status != TaskStatusStopped {
+			run.Status = TaskStatusFailed
+		}
+		run.MustStore(ctx)
+		n.RunsIDs = append(n.RunsIDs, run.ID)
+	}
+	return nil
+}
@@ -41,9 +41,16 @@ func (n *User) Workspaces(ctx context.Context, after, before *string, first, las
 // sorted by Name.
 func (n *User) WorkspacesIDs(ctx context.Context) []string {
 	var slice []string
+	// A Source that lost a conflict keeps listing the Workspace until it syncs again.
+	seen := map[string]bool{}
 	for _, sourceID := range n.SourcesIDs {
 		source := MustLoadSource(ctx, sourceID)
-		slice = append(slice, source.GetWorkspacesIDs()...)
+		for _, id := range source.GetWorkspacesIDs() {
+			if !seen[id] {
+				seen[id] = true
+				slice = append(slice, id)
+			}
+		}
 	}
 	sort.Slice(slice, func(i, j int) bool {
 		a := MustLoadWorkspace(ctx, slice[i])
@@ -93,23 +100,25 @@ func (n *User) SortSources(ctx context.Context) {
 	sort.Slice(n.SourcesIDs, func(i, j int) bool {
 		a := MustLoadSource(ctx, n.SourcesIDs[i])
 		b := MustLoadSource(ctx, n.SourcesIDs[j])
-		u, v := "", ""
-		switch source := a.(type) {
-		case *DirectorySource:
-			u = source.Directory
-		case *GitSource:
-			u = source.Repository
-		}
-		switch source := b.(type) {
-		case *DirectorySource:
-			v = source.Directory
-		case *GitSource:
-			v = source.Repository
-		}
-		return strings.ToLower(u) < strings.ToLower(v)
+		return strings.ToLower(sourceSortKey(a)) < strings.ToLower(sourceSortKey(b))
 	})
 }
 
+// sourceSortKey returns the string used to sort a Source.
+func sourceSortKey(source Source) string {
+	switch source := source.(type) {
+	case *DirectorySource:
+		return source.Directory
+	case *GitSource:
+		return source.Repository
+	case *HTTPSource:
+		if len(source.URLs) > 0 {
+			return source.URLs[0]
+		}
+	}
+	return ""
+}
+
 // SortKeys sorts the Keys alphabetically.
 func (n *User) SortKeys(ctx context.Context) {
 	sort.Slice(n.KeysIDs, func(i, j int) bool {
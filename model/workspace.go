@@ -38,3 +38,145 @@ func (n *Workspace) SortProjects(ctx context.Context) {
 		return strings.ToLower(a.Slug) < strings.ToLower(b.Slug)
 	})
 }
+
+// BehindCount returns the total number of Commits the Projects are behind.
+func (n *Workspace) BehindCount(ctx context.Context) int {
+	count := 0
+	for _, projectID := range n.ProjectsIDs {
+		count += MustLoadProject(ctx, projectID).BehindCount
+	}
+	return count
+}
+
+// AheadCount returns the total number of Commits the Projects are ahead.
+func (n *Workspace) AheadCount(ctx context.Context) int {
+	count := 0
+	for _, projectID := range n.ProjectsIDs {
+		count += MustLoadProject(ctx, projectID).AheadCount
+	}
+	return count
+}
+
+// DivergedCount returns the number of Projects that have diverged.
+func (n *Workspace) DivergedCount(ctx context.Context) int {
+	count := 0
+	for _, projectID := range n.ProjectsIDs {
+		if MustLoadProject(ctx, projectID).IsDiverged {
+			count++
+		}
+	}
+	return count
+}
+
+// IsBusy returns whether one of the Tasks or Services of the Workspace is busy.
+func (n *Workspace) IsBusy(ctx context.Context) bool {
+	for _, taskID := range n.TasksIDs {
+		if MustLoadTask(ctx, taskID).IsBusy() {
+			return true
+		}
+	}
+	for _, serviceID := range n.ServicesIDs {
+		if MustLoadService(ctx, serviceID).IsBusy() {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteWorkspaceRecursive deletes a Workspace along with its Projects, Tasks, and Services.
+// If some of its child Nodes are busy, they are kept along with the Workspace and it returns ErrBusy.
+func DeleteWorkspaceRecursive(ctx context.Context, id string) error {
+	return LockWorkspaceE(ctx, id, func(workspace *Workspace) error {
+		projectsIDs := workspace.ProjectsIDs
+		tasksIDs := workspace.TasksIDs
+		servicesIDs := workspace.ServicesIDs
+		variablesIDs := workspace.VariablesIDs(ctx)
+		workspace.ProjectsIDs = nil
+		workspace.TasksIDs = nil
+		workspace.ServicesIDs = nil
+		// Store the Workspace first so that it never references deleted Nodes.
+		workspace.MustStore(ctx)
+		if err := workspace.DeleteChildren(ctx, projectsIDs, tasksIDs, servicesIDs, variablesIDs); err != nil {
+			return err
+		}
+		if len(workspace.ProjectsIDs)+len(workspace.TasksIDs)+len(workspace.ServicesIDs) > 0 {
+			workspace.MustStore(ctx)
+			return ErrBusy
+		}
+		return DeleteWorkspace(ctx, id)
+	})
+}
+
+// VariablesIDs returns the IDs of the Variables of all the Tasks and Services of the Workspace.
+func (n *Workspace) VariablesIDs(ctx context.Context) []string {
+	var ids []string
+	for _, taskID := range n.TasksIDs {
+		ids = append(ids, MustLoadTask(ctx, taskID).VariablesIDs...)
+	}
+	for _, serviceID := range n.ServicesIDs {
+		service := MustLoadService(ctx, serviceID)
+		ids = append(ids, service.VariablesIDs...)
+		ids = append(ids, service.AllVariablesIDs...)
+	}
+	return ids
+}
+
+// DeleteChildren deletes Projects, Tasks, Services, and Variables that were removed from the Workspace.
+// Variables are only deleted if they are no longer used by the Workspace.
+// The Nodes that are busy are added back to the Workspace so they can be deleted later.
+// Projects are only deleted if no Task or Service of the Workspace is busy.
+// It doesn't store the Workspace.
+func (n *Workspace) DeleteChildren(ctx context.Context, projectsIDs, tasksIDs, servicesIDs, variablesIDs []string) error {
+	for _, serviceID := range servicesIDs {
+		service := MustLoadService(ctx, serviceID)
+		err := DeleteServiceRecursive(ctx, serviceID)
+		if err == ErrBusy {
+			logDeletionPostponed(ctx, service.LongString(ctx))
+			n.ServicesIDs = append(n.ServicesIDs, serviceID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, taskID := range tasksIDs {
+		task := MustLoadTask(ctx, taskID)
+		err := DeleteTaskRecursive(ctx, taskID)
+		if err == ErrBusy {
+			logDeletionPostponed(ctx, task.LongString(ctx))
+			n.TasksIDs = append(n.TasksIDs, taskID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Variables are shared by a Task and a Service with the same name.
+	for _, variableID := range subtractIDs(variablesIDs, n.VariablesIDs(ctx)) {
+		if _, err := LoadVariable(ctx, variableID); err == ErrNotFound {
+			// Duplicate ID.
+			continue
+		}
+		if err := DeleteVariable(ctx, variableID); err != nil {
+			return err
+		}
+	}
+	if n.IsBusy(ctx) {
+		// A running Task or Service could still need the Projects.
+		n.ProjectsIDs = append(n.ProjectsIDs, projectsIDs...)
+		return nil
+	}
+	for _, projectID := range projectsIDs {
+		project := MustLoadProject(ctx, projectID)
+		err := DeleteProjectRecursive(ctx, projectID)
+		if err == ErrBusy {
+			logDeletionPostponed(ctx, project.LongString(ctx))
+			n.ProjectsIDs = append(n.ProjectsIDs, projectID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/mock"
+	"groundcontrol/relay"
+)
+
+func TestDeleteOrphanedWorkspaces(t *testing.T) {
+	workspaceID := relay.EncodeID(NodeTypeWorkspace, "test")
+	projectID := relay.EncodeID(NodeTypeProject, "test", "project")
+	taskID := relay.EncodeID(NodeTypeTask, "test", "task")
+	serviceID := relay.EncodeID(NodeTypeService, "test", "service")
+
+	// setStatus emulates the service manager.
+	setStatus := func(status ServiceStatus) func(context.Context, string) error {
+		return func(ctx context.Context, id string) error {
+			MustLockService(ctx, id, func(service *Service) {
+				service.Status = status
+				service.MustStore(ctx)
+			})
+			return nil
+		}
+	}
+	tests := []struct {
+		name          string
+		taskStatus    TaskStatus
+		serviceStatus ServiceStatus
+		stop          func(context.Context, string) error
+		wantDeleted   bool
+	}{{
+		"idle",
+		TaskStatusStopped,
+		ServiceStatusStopped,
+		nil,
+		true,
+	}, {
+		"running task",
+		TaskStatusRunning,
+		ServiceStatusStopped,
+		nil,
+		false,
+	}, {
+		"running service",
+		TaskStatusStopped,
+		ServiceStatusRunning,
+		setStatus(ServiceStatusStopping),
+		false,
+	}, {
+		"stopping service",
+		TaskStatusStopped,
+		ServiceStatusReady,
+		setStatus(ServiceStatusStopping),
+		false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			services := mock.NewMockServices(ctrl)
+			if tt.stop != nil {
+				services.EXPECT().Stop(gomock.Any(), serviceID).DoAndReturn(tt.stop)
+			}
+			ctx := newTestContext("")
+			appCtx := appcontext.Get(ctx)
+			appCtx.Services = services
+
+			(&Workspace{
+				ID:          workspaceID,
+				Slug:        "test",
+				SourceID:    "source",
+				ProjectsIDs: []string{projectID},
+				TasksIDs:    []string{taskID},
+				ServicesIDs: []string{serviceID},
+			}).MustStore(ctx)
+			(&Project{ID: projectID, Slug: "project", WorkspaceID: workspaceID}).MustStore(ctx)
+			(&Task{ID: taskID, Name: "task", Status: tt.taskStatus, WorkspaceID: workspaceID}).MustStore(ctx)
+			(&Service{ID: serviceID, Name: "service", Status: tt.serviceStatus, WorkspaceID: workspaceID}).MustStore(ctx)
+
+			keptIDs := deleteOrphanedWorkspaces(ctx, "source", []string{workspaceID})
+
+			_, err := LoadWorkspace(ctx, workspaceID)
+			_, projectErr := LoadProject(ctx, projectID)
+			if tt.wantDeleted {
+				assert.Empty(t, keptIDs)
+				assert.Equal(t, ErrNotFound, err)
+				assert.Equal(t, ErrNotFound, projectErr)
+				return
+			}
+			assert.Equal(t, []string{workspaceID}, keptIDs)
+			assert.NoError(t, err)
+			assert.NoError(t, projectErr, "projects are kept while the workspace is busy")
+		})
+	}
+}
+
+func TestDeleteOrphanedWorkspaces_runningService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	workspaceID := relay.EncodeID(NodeTypeWorkspace, "test")
+	serviceID := relay.EncodeID(NodeTypeService, "test", "service")
+	services := mock.NewMockServices(ctrl)
+	ctx := newTestContext("")
+	appcontext.Get(ctx).Services = services
+
+	(&Workspace{ID: workspaceID, Slug: "test", SourceID: "source", ServicesIDs: []string{serviceID}}).MustStore(ctx)
+	(&Service{ID: serviceID, Name: "service", Status: ServiceStatusRunning, WorkspaceID: workspaceID}).MustStore(ctx)
+
+	// The Service is only asked to stop, the sync doesn't wait for it to exit.
+	services.EXPECT().Stop(gomock.Any(), serviceID).Return(nil)
+	keptIDs := deleteOrphanedWorkspaces(ctx, "source", []string{workspaceID})
+	assert.Equal(t, []string{workspaceID}, keptIDs)
+
+	MustLockService(ctx, serviceID, func(service *Service) {
+		service.Status = ServiceStatusStopped
+		service.MustStore(ctx)
+	})
+	keptIDs = deleteOrphanedWorkspaces(ctx, "source", keptIDs)
+	assert.Empty(t, keptIDs)
+	_, err := LoadService(ctx, serviceID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestTaskConfig_storeNodes_busy(t *testing.T) {
+	ctx := newTestContext("")
+	workspaceID := relay.EncodeID(NodeTypeWorkspace, "test")
+	taskID := relay.EncodeID(NodeTypeTask, "test", "task")
+	(&Task{ID: taskID, Name: "task", Status: TaskStatusStopped, WorkspaceID: workspaceID}).MustStore(ctx)
+	config := TaskConfig{Name: "task", Steps: []StepConfig{
+		{Commands: []string{"make"}},
+		{Commands: []string{"make install"}},
+	}}
+	_, err := config.storeNodes(ctx, workspaceID, "test", nil)
+	assert.NoError(t, err)
+	task := MustLoadTask(ctx, taskID)
+	removedStepID := task.StepsIDs[1]
+	removedCommandID := MustLoadStep(ctx, removedStepID).CommandsIDs[0]
+
+	MustLockTask(ctx, taskID, func(task *Task) {
+		task.Status = TaskStatusQueued
+		task.MustStore(ctx)
+	})
+	config.Steps = config.Steps[:1]
+	_, err = config.storeNodes(ctx, workspaceID, "test", nil)
+	assert.NoError(t, err)
+	_, err = LoadStep(ctx, removedStepID)
+	assert.NoError(t, err, "the busy Task could still need the removed Step")
+
+	MustLockTask(ctx, taskID, func(task *Task) {
+		task.Status = TaskStatusStopped
+		task.MustStore(ctx)
+	})
+	_, err = config.storeNodes(ctx, workspaceID, "test", nil)
+	assert.NoError(t, err)
+	_, err = LoadStep(ctx, removedStepID)
+	assert.Equal(t, ErrNotFound, err, "the removed Step is deleted once the Task is idle")
+	_, err = LoadCommand(ctx, removedCommandID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDeleteOrphanedWorkspaces_otherSource(t *testing.T) {
+	ctx := newTestContext("")
+	workspace := &Workspace{ID: relay.EncodeID(NodeTypeWorkspace, "test"), Slug: "test", SourceID: "other"}
+	workspace.MustStore(ctx)
+
+	keptIDs := deleteOrphanedWorkspaces(ctx, "source", []string{workspace.ID})
+	assert.Empty(t, keptIDs)
+	_, err := LoadWorkspace(ctx, workspace.ID)
+	assert.NoError(t, err)
+}
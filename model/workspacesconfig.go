@@ -18,9 +18,15 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
+	"groundcontrol/appcontext"
 	"groundcontrol/relay"
 )
 
@@ -43,10 +49,15 @@ type WorkspaceConfig struct {
 
 // ProjectConfig contains all the data in a YAML project config file.
 type ProjectConfig struct {
-	Slug        string  `json:"slug"`
-	Repository  string  `json:"repository"`
-	Reference   string  `json:"reference"`
-	Description *string `json:"description"`
+	Slug         string            `json:"slug"`
+	Repository   string            `json:"repository"`
+	Remotes      map[string]string `json:"remotes"`
+	Reference    string            `json:"reference"`
+	Description  *string           `json:"description"`
+	Depth        int               `json:"depth"`
+	SingleBranch bool              `json:"singleBranch" yaml:"single-branch"`
+
+	referenceOverridden bool
 }
 
 // TaskConfig contains all the data in a YAML task config file.
@@ -60,48 +71,100 @@ type TaskConfig struct {
 type VariableConfig struct {
 	Name    string  `json:"name"`
 	Default *string `json:"default"`
+
+	defaultOverridden bool
 }
 
 // StepConfig contains all the data in a YAML step config file.
 type StepConfig struct {
-	Projects []string `json:"projects"`
-	Commands []string `json:"commands"`
+	Projects    []string `json:"projects"`
+	Commands    []string `json:"commands"`
+	Parallel    bool     `json:"parallel"`
+	Concurrency int      `json:"concurrency"`
 }
 
 // ServiceConfig contains all the data in a YAML service config file.
 type ServiceConfig struct {
-	Name      string           `json:"name"`
-	Variables []VariableConfig `json:"variables"`
-	Project   string           `json:"project"`
-	Needs     []string         `json:"needs"`
-	Command   string           `json:"command"`
-	Before    []string         `json:"before"`
-	After     []string         `json:"after"`
+	Name        string             `json:"name"`
+	Variables   []VariableConfig   `json:"variables"`
+	Project     string             `json:"project"`
+	Needs       []string           `json:"needs"`
+	Command     string             `json:"command"`
+	Before      []string           `json:"before"`
+	After       []string           `json:"after"`
+	Healthcheck *HealthcheckConfig `json:"healthcheck"`
+	Restart     string             `json:"restart"`
+	MaxRetries  *int               `json:"maxRetries" yaml:"max-retries"`
+	Backoff     time.Duration      `json:"backoff"`
+
+	commandOverridden bool
 }
 
+// HealthcheckConfig contains all the data in a YAML health check config file.
+type HealthcheckConfig struct {
+	Port       *int          `json:"port"`
+	URL        *string       `json:"url"`
+	Status     *int          `json:"status"`
+	Command    *string       `json:"command"`
+	LogPattern *string       `json:"logPattern" yaml:"log-pattern"`
+	Interval   time.Duration `json:"interval"`
+	Timeout    time.Duration `json:"timeout"`
+}
+
+// Health check defaults.
+const (
+	DefaultHealthcheckStatus   = http.StatusOK
+	DefaultHealthcheckInterval = time.Second
+	DefaultHealthcheckTimeout  = time.Minute
+)
+
+// Service restart defaults.
+const (
+	DefaultServiceMaxRetries = 5
+	DefaultServiceBackoff    = time.Second
+)
+
 // storeNodes stores nodes for the content of the config.
-// It returns the IDs of the workspaces storeed.
-func (c WorkspacesConfig) storeNodes(ctx context.Context, sourceID string) ([]string, error) {
-	var workspaceIDs []string
+// Workspaces that are also defined by a Source that takes precedence are
+// skipped. It returns the IDs of the workspaces stored and the conflicts with
+// other Sources.
+func (c WorkspacesConfig) storeNodes(ctx context.Context, source Source) ([]string, []*SourceConflict, error) {
+	var (
+		workspaceIDs []string
+		conflicts    []*SourceConflict
+	)
 
 	for _, workspaceConfig := range c.Workspaces {
-		id, err := workspaceConfig.storeNodes(ctx, sourceID)
+		id := relay.EncodeID(NodeTypeWorkspace, workspaceConfig.Slug)
+		found := findSlugConflicts(ctx, source, id, workspaceConfig.Slug)
+		conflicts = append(conflicts, found...)
+
+		if !hasPrecedence(found) {
+			continue
+		}
+
+		id, err := workspaceConfig.storeNodes(ctx, source.GetID())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		workspaceIDs = append(workspaceIDs, id)
 	}
 
-	return workspaceIDs, nil
+	return workspaceIDs, conflicts, nil
 }
 
 // storeNodes stores nodes for the content of the config.
 // It returns the ID of the workspace storeed.
 func (c WorkspaceConfig) storeNodes(ctx context.Context, sourceID string) (string, error) {
 	id := relay.EncodeID(NodeTypeWorkspace, c.Slug)
+	c = c.applyOverrides(appcontext.Get(ctx).Overrides)
 
 	err := MustLockOrNewWorkspaceE(ctx, id, func(workspace *Workspace, isNew bool) error {
+		wasProjectsIDs := workspace.ProjectsIDs
+		wasTasksIDs := workspace.TasksIDs
+		wasServicesIDs := workspace.ServicesIDs
+		wasVariablesIDs := workspace.VariablesIDs(ctx)
 		workspace.Slug = c.Slug
 		workspace.Name = c.Name
 		workspace.Description = c.Description
@@ -165,6 +228,21 @@ func (c WorkspaceConfig) storeNodes(ctx context.Context, sourceID string) (strin
 			}
 		}
 
+		// Store the Workspace before deleting the Nodes that were removed from the config
+		// so that it never references deleted Nodes.
+		workspace.MustStore(ctx)
+
+		err := workspace.DeleteChildren(
+			ctx,
+			subtractIDs(wasProjectsIDs, workspace.ProjectsIDs),
+			subtractIDs(wasTasksIDs, workspace.TasksIDs),
+			subtractIDs(wasServicesIDs, workspace.ServicesIDs),
+			wasVariablesIDs,
+		)
+		if err != nil {
+			return err
+		}
+
 		workspace.MustStore(ctx)
 
 		return nil
@@ -176,6 +254,58 @@ func (c WorkspaceConfig) storeNodes(ctx context.Context, sourceID string) (strin
 	return id, nil
 }
 
+// applyOverrides returns a copy of the config with the overrides of the user
+// applied.
+func (c WorkspaceConfig) applyOverrides(overrides appcontext.Overrides) WorkspaceConfig {
+	if overrides == nil {
+		return c
+	}
+
+	projects := make([]ProjectConfig, len(c.Projects))
+	for i, project := range c.Projects {
+		if reference, ok := overrides.ProjectReference(c.Slug, project.Slug); ok {
+			project.Reference = reference
+			project.referenceOverridden = true
+		}
+		projects[i] = project
+	}
+	c.Projects = projects
+
+	tasks := make([]TaskConfig, len(c.Tasks))
+	for i, task := range c.Tasks {
+		task.Variables = c.applyVariablesOverrides(overrides, task.Variables)
+		tasks[i] = task
+	}
+	c.Tasks = tasks
+
+	services := make([]ServiceConfig, len(c.Services))
+	for i, service := range c.Services {
+		if command, ok := overrides.ServiceCommand(c.Slug, service.Name); ok {
+			service.Command = command
+			service.commandOverridden = true
+		}
+		service.Variables = c.applyVariablesOverrides(overrides, service.Variables)
+		services[i] = service
+	}
+	c.Services = services
+
+	return c
+}
+
+// applyVariablesOverrides returns a copy of variables with the default values
+// overridden by the user.
+func (c WorkspaceConfig) applyVariablesOverrides(overrides appcontext.Overrides, variables []VariableConfig) []VariableConfig {
+	result := make([]VariableConfig, len(variables))
+	for i, variable := range variables {
+		if value, ok := overrides.VariableDefault(c.Slug, variable.Name); ok {
+			variable.Default = &value
+			variable.defaultOverridden = true
+		}
+		result[i] = variable
+	}
+	return result
+}
+
 // storeNodes stores nodes for the content of the config.
 // It returns the ID of the project storeed.
 func (c ProjectConfig) storeNodes(
@@ -192,8 +322,12 @@ func (c ProjectConfig) storeNodes(
 	MustLockOrNewProject(ctx, id, func(project *Project, isNew bool) {
 		project.Slug = c.Slug
 		project.Repository = c.Repository
+		project.Remotes = c.remotes()
 		project.Reference = c.Reference
+		project.IsReferenceOverridden = c.referenceOverridden
 		project.Description = c.Description
+		project.Depth = c.Depth
+		project.SingleBranch = c.SingleBranch
 		project.WorkspaceID = workspaceID
 
 		if isNew {
@@ -208,6 +342,22 @@ func (c ProjectConfig) storeNodes(
 	return id
 }
 
+// remotes returns the Remotes of the project sorted by name.
+// The origin remote is always the repository so it is ignored.
+func (c ProjectConfig) remotes() []*Remote {
+	remotes := []*Remote{}
+	for name, url := range c.Remotes {
+		if name == OriginRemote {
+			continue
+		}
+		remotes = append(remotes, &Remote{Name: name, URL: url})
+	}
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].Name < remotes[j].Name
+	})
+	return remotes
+}
+
 // storeNodes stores nodes for the content of the config.
 // It returns the ID of the task storeed.
 func (c TaskConfig) storeNodes(
@@ -223,6 +373,8 @@ func (c TaskConfig) storeNodes(
 	)
 
 	err := MustLockOrNewTaskE(ctx, id, func(task *Task, isNew bool) error {
+		wasStepsIDs := task.StepsIDs
+		wasCommandsIDs := task.commandsIDs(ctx)
 		task.Name = c.Name
 		task.WorkspaceID = workspaceID
 		task.VariablesIDs = nil
@@ -231,6 +383,10 @@ func (c TaskConfig) storeNodes(
 		if isNew {
 			task.Status = TaskStatusStopped
 
+			if err := task.loadRuns(ctx, workspaceSlug); err != nil {
+				return err
+			}
+
 			// We need to make sure the task exists before child nodes refer to it.
 			task.MustStore(ctx)
 		}
@@ -263,7 +419,16 @@ func (c TaskConfig) storeNodes(
 
 		task.MustStore(ctx)
 
-		return nil
+		orphanStepsIDs := subtractIDs(wasStepsIDs, task.StepsIDs)
+		orphanCommandsIDs := subtractIDs(wasCommandsIDs, task.commandsIDs(ctx))
+
+		if task.IsBusy() {
+			// The running Task could still need the removed Steps and Commands.
+			task.postponeOrphansDeletion(orphanStepsIDs, orphanCommandsIDs)
+			return nil
+		}
+
+		return task.deleteOrphans(ctx, orphanStepsIDs, orphanCommandsIDs)
 	})
 	if err != nil {
 		return "", err
@@ -289,6 +454,7 @@ func (c VariableConfig) storeNodes(
 	MustLockOrNewVariable(ctx, id, func(variable *Variable, _ bool) {
 		variable.Name = c.Name
 		variable.Default = c.Default
+		variable.IsDefaultOverridden = c.defaultOverridden
 
 		variable.MustStore(ctx)
 	})
@@ -317,6 +483,15 @@ func (c StepConfig) storeNodes(
 		step.TaskID = taskID
 		step.ProjectsIDs = nil
 		step.CommandsIDs = nil
+		step.Concurrency = 1
+
+		if c.Parallel {
+			step.Concurrency = 0
+		}
+
+		if c.Concurrency > 0 {
+			step.Concurrency = c.Concurrency
+		}
 
 		for _, slug := range c.Projects {
 			id, ok := projectSlugToID[slug]
@@ -368,15 +543,46 @@ func (c ServiceConfig) storeNodes(
 		service.Name = c.Name
 		service.WorkspaceID = workspaceID
 		service.Command = c.Command
+		service.IsCommandOverridden = c.commandOverridden
 		service.VariablesIDs = nil
 		service.NeedsIDs = nil
 		service.BeforeIDs = nil
 		service.AfterIDs = nil
+		service.Healthcheck = nil
+		service.Restart = RestartPolicyNever
+		service.MaxRetries = DefaultServiceMaxRetries
+		service.Backoff = int(DefaultServiceBackoff / time.Millisecond)
 
 		if isNew {
 			service.Status = ServiceStatusStopped
 		}
 
+		if c.Restart != "" {
+			restart := RestartPolicy(strings.ToUpper(strings.Replace(c.Restart, "-", "_", -1)))
+			if !restart.IsValid() {
+				return ErrRestartPolicy
+			}
+
+			service.Restart = restart
+		}
+
+		if c.MaxRetries != nil {
+			service.MaxRetries = *c.MaxRetries
+		}
+
+		if c.Backoff > 0 {
+			service.Backoff = int(c.Backoff / time.Millisecond)
+		}
+
+		if c.Healthcheck != nil {
+			healthcheck, err := c.Healthcheck.newHealthcheck()
+			if err != nil {
+				return err
+			}
+
+			service.Healthcheck = healthcheck
+		}
+
 		if c.Project != "" {
 			projectID, ok := projectSlugToID[c.Project]
 			if !ok {
@@ -425,6 +631,39 @@ func (c ServiceConfig) storeNodes(
 	return id, nil
 }
 
+// newHealthcheck creates a Healthcheck from the config, using defaults for missing values.
+func (c HealthcheckConfig) newHealthcheck() (*Healthcheck, error) {
+	if c.LogPattern != nil {
+		if _, err := regexp.Compile(*c.LogPattern); err != nil {
+			return nil, err
+		}
+	}
+
+	healthcheck := Healthcheck{
+		Port:       c.Port,
+		URL:        c.URL,
+		Status:     DefaultHealthcheckStatus,
+		Command:    c.Command,
+		LogPattern: c.LogPattern,
+		Interval:   int(DefaultHealthcheckInterval / time.Millisecond),
+		Timeout:    int(DefaultHealthcheckTimeout / time.Millisecond),
+	}
+
+	if c.Status != nil {
+		healthcheck.Status = *c.Status
+	}
+
+	if c.Interval > 0 {
+		healthcheck.Interval = int(c.Interval / time.Millisecond)
+	}
+
+	if c.Timeout > 0 {
+		healthcheck.Timeout = int(c.Timeout / time.Millisecond)
+	}
+
+	return &healthcheck, nil
+}
+
 // SetNeeds sets nodes with the Services it needs.
 // It must be called after all the Service nodes have been created.
 func (c ServiceConfig) SetNeeds(
@@ -479,14 +718,19 @@ func (c ServiceConfig) SetDependencies(ctx context.Context, workspaceSlug string
 
 // LoadWorkspacesConfigYAML loads a config from a YAML file.
 func LoadWorkspacesConfigYAML(filename string) (*WorkspacesConfig, error) {
-	config := WorkspacesConfig{
-		Filename: filename,
-	}
-
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
+	return ParseWorkspacesConfigYAML(filename, bytes)
+}
+
+// ParseWorkspacesConfigYAML parses the content of a YAML workspaces config file.
+func ParseWorkspacesConfigYAML(filename string, bytes []byte) (*WorkspacesConfig, error) {
+	config := WorkspacesConfig{
+		Filename: filename,
+	}
+
 	return &config, yaml.UnmarshalStrict(bytes, &config)
 }
@@ -0,0 +1,73 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"groundcontrol/config"
+)
+
+func TestWorkspaceConfig_applyOverrides(t *testing.T) {
+	reference := "refs/heads/feature"
+	command := "make run-debug"
+	port := "3001"
+	defaultPort := "3000"
+	overrides := &config.Overrides{}
+	overrides.SetProjectReference("workspace", "project", &reference)
+	overrides.SetServiceCommand("workspace", "Service", &command)
+	overrides.SetVariableDefault("workspace", "PORT", &port)
+	// Overrides of other workspaces are ignored.
+	overrides.SetServiceCommand("other", "Other", &command)
+
+	c := WorkspaceConfig{
+		Slug: "workspace",
+		Projects: []ProjectConfig{
+			{Slug: "project", Reference: "refs/heads/master"},
+			{Slug: "other", Reference: "refs/heads/master"},
+		},
+		Tasks: []TaskConfig{{
+			Name:      "Task",
+			Variables: []VariableConfig{{Name: "PORT", Default: &defaultPort}, {Name: "HOST"}},
+		}},
+		Services: []ServiceConfig{
+			{Name: "Service", Command: "make run", Variables: []VariableConfig{{Name: "PORT"}}},
+			{Name: "Other", Command: "make other"},
+		},
+	}
+
+	got := c.applyOverrides(overrides)
+
+	assert.Equal(t, []ProjectConfig{
+		{Slug: "project", Reference: reference, referenceOverridden: true},
+		{Slug: "other", Reference: "refs/heads/master"},
+	}, got.Projects)
+	assert.Equal(t, []VariableConfig{
+		{Name: "PORT", Default: &port, defaultOverridden: true},
+		{Name: "HOST"},
+	}, got.Tasks[0].Variables)
+	assert.Equal(t, command, got.Services[0].Command)
+	assert.True(t, got.Services[0].commandOverridden)
+	assert.Equal(t, []VariableConfig{{Name: "PORT", Default: &port, defaultOverridden: true}}, got.Services[0].Variables)
+	assert.Equal(t, "make other", got.Services[1].Command)
+	assert.False(t, got.Services[1].commandOverridden)
+
+	assert.Equal(t, "refs/heads/master", c.Projects[0].Reference, "the config isn't modified")
+	assert.Equal(t, &defaultPort, c.Tasks[0].Variables[0].Default)
+	assert.Equal(t, "make run", c.Services[0].Command)
+	assert.Equal(t, c, c.applyOverrides(nil))
+}
@@ -146,7 +146,7 @@ func (m *Plugin) MutateConfig(cfg *config.Config) error {
 		case ast.Interface, ast.Union:
 			it := &Interface{
 				Description: schemaType.Description,
-				Name:        templates.ToGo(schemaType.Name),
+				Name:        util.ToGoTypeName(schemaType.Name),
 			}
 
 			b.Interfaces = append(b.Interfaces, it)
@@ -156,11 +156,11 @@ func (m *Plugin) MutateConfig(cfg *config.Config) error {
 			}
 			it := &Object{
 				Description: schemaType.Description,
-				Name:        templates.ToGo(schemaType.Name),
+				Name:        util.ToGoTypeName(schemaType.Name),
 			}
 
 			for _, implementor := range schema.GetImplements(schemaType) {
-				it.Implements = append(it.Implements, templates.ToGo(implementor.Name))
+				it.Implements = append(it.Implements, util.ToGoTypeName(implementor.Name))
 			}
 
 			if strings.HasSuffix(schemaType.Name, "Connection") {
@@ -179,7 +179,7 @@ func (m *Plugin) MutateConfig(cfg *config.Config) error {
 					return err
 				}
 
-				name := field.Name
+				name := templates.ToGo(field.Name)
 				if nameOveride := cfg.Models[schemaType.Name].Fields[field.Name].FieldName; nameOveride != "" {
 					name = nameOveride
 				}
@@ -200,7 +200,7 @@ func (m *Plugin) MutateConfig(cfg *config.Config) error {
 
 				fd := schema.Types[field.Type.Name()]
 				it.Fields = append(it.Fields, &Field{
-					Name:        templates.ToGo(name),
+					Name:        name,
 					Type:        util.CopyModifiersFromAst(field.Type, fd, typ),
 					Description: field.Description,
 					Tag:         `json:"` + field.Name + `"`,
@@ -214,7 +214,7 @@ func (m *Plugin) MutateConfig(cfg *config.Config) error {
 			b.Models = append(b.Models, it)
 		case ast.Enum:
 			it := &Enum{
-				Name:        templates.ToGo(schemaType.Name),
+				Name:        util.ToGoTypeName(schemaType.Name),
 				Raw:         schemaType.Name,
 				Description: schemaType.Description,
 			}
@@ -301,9 +301,9 @@ func (m *Plugin) connection(
 	}
 
 	build.Connections = append(build.Connections, &Connection{
-		Name:     templates.ToGo(connectionName),
-		NodeName: templates.ToGo(nodeName),
-		EdgeName: templates.ToGo(edgeName),
+		Name:     util.ToGoTypeName(connectionName),
+		NodeName: util.ToGoTypeName(nodeName),
+		EdgeName: util.ToGoTypeName(edgeName),
 		Edge:     util.CopyModifiersFromAst(edgeType, edgeDef, edgeGoType),
 		Node:     util.CopyModifiersFromAst(nodeType, nodeDef, nodeGoType),
 	})
@@ -343,7 +343,7 @@ func (m *Plugin) relate(
 	obj.Relates = append(obj.Relates, &Relate{
 		Description:   field.Description,
 		Name:          templates.ToGo(name),
-		TypeName:      templates.ToGo(field.Type.Name()),
+		TypeName:      util.ToGoTypeName(field.Type.Name()),
 		Type:          util.CopyModifiersFromAst(field.Type, fd, relateGoType),
 		NonNull:       field.Type.NonNull,
 		GoIDFieldName: idName,
@@ -419,7 +419,7 @@ func (m *Plugin) paginate(
 
 	paginates := &Paginate{
 		Name:           templates.ToGo(name),
-		NodeName:       templates.ToGo(nodeName),
+		NodeName:       util.ToGoTypeName(nodeName),
 		Connection:     util.CopyModifiersFromAst(connectionType, connectionDef, connectionGoType),
 		Edge:           util.CopyModifiersFromAst(edgeType, edgeDef, edgeGoType),
 		Node:           util.CopyModifiersFromAst(nodeType, nodeDef, nodeGoType),
@@ -125,7 +125,7 @@ func (p *Plugin) stored(
 
 	build.Stored = append(build.Stored, &Stored{
 		Type:     util.CopyModifiersFromAst(gqlType, def, goType),
-		TypeName: templates.ToGo(name),
+		TypeName: util.ToGoTypeName(name),
 	})
 
 	return nil
@@ -149,7 +149,7 @@ func (p *Plugin) deleted(
 
 	build.Deleted = append(build.Deleted, &Deleted{
 		Type:     util.CopyModifiersFromAst(gqlType, def, goType),
-		TypeName: templates.ToGo(name),
+		TypeName: util.ToGoTypeName(name),
 	})
 
 	return nil
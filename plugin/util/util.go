@@ -29,6 +29,19 @@ func NormalizeVendor(pkg string) string {
 	return modifiers + parts[len(parts)-1]
 }
 
+var goTypeNameRegex = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// ToGoTypeName returns the Go name of a GraphQL type. Names that are already
+// valid exported Go identifiers are kept as is so that initialisms such as
+// HTTP are preserved.
+func ToGoTypeName(name string) string {
+	if goTypeNameRegex.MatchString(name) {
+		return name
+	}
+
+	return templates.ToGo(name)
+}
+
 var invalidPackageNameChar = regexp.MustCompile(`[^\w]`)
 
 func SanitizePackageName(pkg string) string {
@@ -41,7 +54,7 @@ func GoType(cfg *config.Config, schema *ast.Schema, binder *config.Binder, name
 		return binder.FindType(pkg, typeName)
 	}
 
-	return types.NewNamed(types.NewTypeName(0, cfg.Model.Pkg(), templates.ToGo(name), nil), nil, nil), nil
+	return types.NewNamed(types.NewTypeName(0, cfg.Model.Pkg(), ToGoTypeName(name), nil), nil, nil), nil
 }
 
 func CopyModifiersFromAst(t *ast.Type, d *ast.Definition, base types.Type) types.Type {
@@ -0,0 +1,37 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolver
+
+import (
+	"context"
+
+	"groundcontrol/job"
+	"groundcontrol/model"
+)
+
+func (r *mutationResolver) ApproveSource(ctx context.Context, id string, commit model.Hash) (model.Source, error) {
+	err := model.LockGitSourceE(ctx, id, func(source *model.GitSource) error {
+		return source.Approve(ctx, commit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Sync the Source so that its Workspaces use the approved commands.
+	_, err = job.SyncGitSource(ctx, id, true)
+	if err != nil && err != job.ErrDuplicate {
+		return nil, err
+	}
+	return model.LoadSource(ctx, id)
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolver
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/job"
+	"groundcontrol/model"
+)
+
+// saveOverrides saves the overrides and syncs the Source of a Workspace so
+// that its nodes use them.
+func saveOverrides(ctx context.Context, workspace *model.Workspace) error {
+	if err := appcontext.Get(ctx).Overrides.Save(); err != nil {
+		return err
+	}
+	return job.SyncSourceAndWait(ctx, workspace.SourceID, true)
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolver
+
+import (
+	"context"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+	"groundcontrol/relay"
+)
+
+func (r *mutationResolver) OverrideVariableDefault(ctx context.Context, id string, value *string) (*model.Variable, error) {
+	variable, err := model.LoadVariable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Variables don't reference their Workspace, but its slug is part of their ID.
+	parts, err := relay.DecodeID(id)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := model.LoadWorkspace(ctx, relay.EncodeID(model.NodeTypeWorkspace, parts[1]))
+	if err != nil {
+		return nil, err
+	}
+	appcontext.Get(ctx).Overrides.SetVariableDefault(workspace.Slug, variable.Name, value)
+	if err := saveOverrides(ctx, workspace); err != nil {
+		return nil, err
+	}
+	return model.LoadVariable(ctx, variable.ID)
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolver
+
+import (
+	"context"
+
+	"groundcontrol/job"
+	"groundcontrol/model"
+)
+
+func (r *mutationResolver) RestoreWorkspace(ctx context.Context, snapshot string) ([]model.Job, error) {
+	jobIDs, err := job.RestoreWorkspace(ctx, snapshot, true)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []model.Job
+	for _, id := range jobIDs {
+		jobs = append(jobs, *model.MustLoadJob(ctx, id))
+	}
+	return jobs, nil
+}
@@ -29,6 +29,10 @@ func (r *subscriptionResolver) SourceDeleted(ctx context.Context, id *string, la
 	if err != nil {
 		return nil, err
 	}
+	httpCh, err := r.HTTPSourceDeleted(ctx, id, lastMessageID)
+	if err != nil {
+		return nil, err
+	}
 	ch := make(chan model.Source, r.AppCtx.SubChannelSize)
 	go func() {
 		for {
@@ -45,6 +49,11 @@ func (r *subscriptionResolver) SourceDeleted(ctx context.Context, id *string, la
 				case ch <- node:
 				default:
 				}
+			case node := <-httpCh:
+				select {
+				case ch <- node:
+				default:
+				}
 			}
 		}
 	}()
@@ -29,6 +29,10 @@ func (r *subscriptionResolver) SourceStored(ctx context.Context, id *string, las
 	if err != nil {
 		return nil, err
 	}
+	httpCh, err := r.HTTPSourceStored(ctx, id, lastMessageID)
+	if err != nil {
+		return nil, err
+	}
 	ch := make(chan model.Source, r.AppCtx.SubChannelSize)
 	go func() {
 		for {
@@ -45,6 +49,11 @@ func (r *subscriptionResolver) SourceStored(ctx context.Context, id *string, las
 				case ch <- node:
 				default:
 				}
+			case node := <-httpCh:
+				select {
+				case ch <- node:
+				default:
+				}
 			}
 		}
 	}()
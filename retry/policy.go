@@ -0,0 +1,56 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"time"
+)
+
+// Policy defines how a failed operation is retried.
+type Policy struct {
+	// MaxAttempts is the maximum number of times the operation is executed.
+	MaxAttempts int
+	// Backoff is how long to wait before the first retry. It doubles after each attempt.
+	Backoff time.Duration
+	// MaxBackoff is the longest time to wait before a retry, or zero for no limit.
+	MaxBackoff time.Duration
+	// IsRetryable returns whether an operation that failed with the given error should be retried.
+	// If it is nil all errors are retried.
+	IsRetryable func(error) bool
+}
+
+// ShouldRetry returns whether to retry after the given attempt failed with an error.
+// Attempts start at one.
+func (p *Policy) ShouldRetry(attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.IsRetryable == nil || p.IsRetryable(err)
+}
+
+// Delay returns how long to wait before retrying after the given attempt.
+func (p *Policy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt; i++ {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_ShouldRetry(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	p := &Policy{
+		MaxAttempts: 3,
+		IsRetryable: func(err error) bool { return err != errPermanent },
+	}
+	assert.True(t, p.ShouldRetry(1, errors.New("transient")))
+	assert.True(t, p.ShouldRetry(2, errors.New("transient")))
+	assert.False(t, p.ShouldRetry(3, errors.New("transient")))
+	assert.False(t, p.ShouldRetry(1, errPermanent))
+	assert.False(t, (*Policy)(nil).ShouldRetry(1, errors.New("transient")))
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := &Policy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.Delay(1))
+	assert.Equal(t, 2*time.Second, p.Delay(2))
+	assert.Equal(t, 4*time.Second, p.Delay(3))
+	assert.Equal(t, 5*time.Second, p.Delay(4))
+	assert.Equal(t, 5*time.Second, p.Delay(100))
+}
@@ -18,5 +18,8 @@ import "errors"
 
 // Errors.
 var (
-	ErrStatus = errors.New("it has the wrong status")
+	ErrStatus     = errors.New("it has the wrong status")
+	ErrNotReady   = errors.New("it did not become ready")
+	ErrHTTPStatus = errors.New("the health check URL returned an unexpected status")
+	ErrNoLogMatch = errors.New("the output did not match the log pattern")
 )
@@ -0,0 +1,193 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+	"groundcontrol/util"
+)
+
+// logMatcher looks for a pattern in the output of a Service.
+// A nil logMatcher is always matched.
+type logMatcher struct {
+	pattern *regexp.Regexp
+	mu      sync.Mutex
+	matched bool
+}
+
+// newLogMatcher creates a logMatcher for the log pattern of a Healthcheck.
+// It returns nil if there is no log pattern.
+func newLogMatcher(healthcheck *model.Healthcheck) (*logMatcher, error) {
+	if healthcheck == nil || healthcheck.LogPattern == nil {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(*healthcheck.LogPattern)
+	if err != nil {
+		return nil, err
+	}
+	return &logMatcher{pattern: pattern}, nil
+}
+
+// wrap returns a LineWriter that looks for the pattern before writing lines.
+func (m *logMatcher) wrap(write util.LineWriter) util.LineWriter {
+	if m == nil {
+		return write
+	}
+	return func(ctx context.Context, ownerID, msg string, a ...interface{}) string {
+		line := msg
+		if len(a) > 0 {
+			line = fmt.Sprintf(msg, a...)
+		}
+		m.mu.Lock()
+		if !m.matched && m.pattern.MatchString(line) {
+			m.matched = true
+		}
+		m.mu.Unlock()
+		return write(ctx, ownerID, msg, a...)
+	}
+}
+
+// isMatched returns whether a line matched the pattern.
+func (m *logMatcher) isMatched() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.matched
+}
+
+// checkHealth probes the Healthcheck of a Service until it passes, then sets the Service as READY.
+// If the Healthcheck times out, the Service is killed so that it fails and its RestartPolicy applies.
+// It gives up if the Service exits.
+func (m *Manager) checkHealth(ctx context.Context, service *model.Service, env []string, matcher *logMatcher) {
+	appCtx := appcontext.Get(ctx)
+	healthcheck := service.Healthcheck
+	timeout := time.After(healthcheck.TimeoutDuration())
+	for {
+		err := m.probe(ctx, service, env, matcher)
+		if err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			appCtx.Log.ErrorWithOwner(
+				ctx,
+				appCtx.SystemID,
+				"%s  health check failed because %s",
+				service.LongString(ctx),
+				err.Error(),
+			)
+			m.kill(ctx, service.ID)
+			return
+		case <-time.After(healthcheck.IntervalDuration()):
+		}
+	}
+	model.MustLockService(ctx, service.ID, func(service *model.Service) {
+		if service.Status != model.ServiceStatusRunning {
+			return
+		}
+		m.setStatus(ctx, service, model.ServiceStatusReady)
+		service.MustStore(ctx)
+	})
+}
+
+// kill cancels the run of a Service that is still RUNNING.
+// Unlike Stop, the Service is considered to have failed.
+func (m *Manager) kill(ctx context.Context, serviceID string) {
+	model.MustLockService(ctx, serviceID, func(service *model.Service) {
+		if service.Status != model.ServiceStatusRunning {
+			return
+		}
+		if actual, ok := m.cancels.Load(serviceID); ok {
+			actual.(context.CancelFunc)()
+		}
+	})
+}
+
+// probe runs all the checks of a Healthcheck once.
+func (m *Manager) probe(ctx context.Context, service *model.Service, env []string, matcher *logMatcher) error {
+	healthcheck := service.Healthcheck
+	ctx, cancel := context.WithTimeout(ctx, healthcheck.IntervalDuration())
+	defer cancel()
+	if healthcheck.Port != nil {
+		if err := probePort(ctx, *healthcheck.Port); err != nil {
+			return err
+		}
+	}
+	if healthcheck.URL != nil {
+		if err := probeURL(ctx, *healthcheck.URL, healthcheck.Status); err != nil {
+			return err
+		}
+	}
+	if healthcheck.Command != nil {
+		if err := m.probeCommand(ctx, service, env, *healthcheck.Command); err != nil {
+			return err
+		}
+	}
+	if !matcher.isMatched() {
+		return ErrNoLogMatch
+	}
+	return nil
+}
+
+func probePort(ctx context.Context, port int) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeURL(ctx context.Context, url string, status int) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != status {
+		return ErrHTTPStatus
+	}
+	return nil
+}
+
+func (m *Manager) probeCommand(ctx context.Context, service *model.Service, env []string, command string) error {
+	appCtx := appcontext.Get(ctx)
+	dir := m.getDir(ctx, service)
+	env = append(os.Environ(), env...)
+	runner, err := appCtx.NewRunner(ioutil.Discard, ioutil.Discard, dir, env, appCtx.RunnerGracefulShutdownTimeout)
+	if err != nil {
+		return err
+	}
+	return runner.Run(ctx, command)
+}
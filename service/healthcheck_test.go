@@ -0,0 +1,145 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/model"
+)
+
+func TestLogMatcher(t *testing.T) {
+	pattern := "listening on \\d+"
+	matcher, err := newLogMatcher(&model.Healthcheck{LogPattern: &pattern})
+	require.NoError(t, err)
+	write := matcher.wrap(func(context.Context, string, string, ...interface{}) string { return "" })
+
+	write(context.Background(), "", "starting")
+	assert.False(t, matcher.isMatched())
+	write(context.Background(), "", "listening on %d", 3000)
+	assert.True(t, matcher.isMatched())
+
+	var none *logMatcher
+	assert.True(t, none.isMatched(), "a nil matcher is always matched")
+}
+
+func TestManager_checkHealth(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	closed, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	pattern := "ready"
+	tests := []struct {
+		name       string
+		port       int
+		logPattern *string
+		log        string
+		wantStatus model.ServiceStatus
+		wantKilled bool
+	}{{
+		"ready",
+		openPort,
+		nil,
+		"",
+		model.ServiceStatusReady,
+		false,
+	}, {
+		"timeout",
+		closedPort,
+		nil,
+		"",
+		model.ServiceStatusRunning,
+		true,
+	}, {
+		"log matched",
+		openPort,
+		&pattern,
+		"ready",
+		model.ServiceStatusReady,
+		false,
+	}, {
+		"log not matched",
+		openPort,
+		&pattern,
+		"starting",
+		model.ServiceStatusRunning,
+		true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext()
+			m := NewManager()
+			service := newTestService(ctx, &model.Service{
+				Healthcheck: &model.Healthcheck{
+					Port:       &tt.port,
+					LogPattern: tt.logPattern,
+					Interval:   10,
+					Timeout:    50,
+				},
+			})
+			matcher, err := newLogMatcher(service.Healthcheck)
+			require.NoError(t, err)
+			if matcher != nil {
+				write := matcher.wrap(func(context.Context, string, string, ...interface{}) string { return "" })
+				write(ctx, service.ID, tt.log)
+			}
+			runCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			m.cancels.Store(service.ID, cancel)
+
+			m.checkHealth(runCtx, service, nil, matcher)
+
+			assert.Equal(t, tt.wantStatus, model.MustLoadService(ctx, service.ID).Status)
+			assert.Equal(t, tt.wantKilled, runCtx.Err() != nil, "killed")
+		})
+	}
+}
+
+func TestManager_launchService_healthcheckTimeout(t *testing.T) {
+	closed, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	ctx := newTestContext()
+	m := NewManager()
+	service := newTestService(ctx, &model.Service{
+		Status:      model.ServiceStatusStarting,
+		Healthcheck: &model.Healthcheck{Port: &port, Interval: 10, Timeout: 50},
+	})
+	lastMsgID := appcontext.Get(ctx).Subs.LastMessageID()
+
+	model.MustLockService(ctx, service.ID, func(service *model.Service) {
+		require.NoError(t, m.launchService(ctx, blockingRunner, service, nil, nil, func() {}))
+	})
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	m.waitTillDone(waitCtx, service.ID, lastMsgID)
+
+	assert.Equal(t, model.ServiceStatusFailed, model.MustLoadService(ctx, service.ID).Status)
+}
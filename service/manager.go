@@ -20,12 +20,17 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"groundcontrol/appcontext"
 	"groundcontrol/model"
+	"groundcontrol/shell"
 	"groundcontrol/util"
 )
 
+// MaxRestartBackoff is the longest time to wait before restarting a Service.
+const MaxRestartBackoff = time.Minute
+
 // Manager manages running and stopping services.
 type Manager struct {
 	cancels sync.Map
@@ -33,6 +38,7 @@ type Manager struct {
 	stoppedCounter  int64
 	startingCounter int64
 	runningCounter  int64
+	readyCounter    int64
 	stoppingCounter int64
 	failedCounter   int64
 }
@@ -43,28 +49,42 @@ func NewManager() *Manager {
 }
 
 // Start starts a Service and its dependencies.
+// A dependency must be ready before the next one is started.
 func (m *Manager) Start(ctx context.Context, serviceID string, env []string) error {
 	service, err := model.LoadService(ctx, serviceID)
 	if err != nil {
 		return err
 	}
+	subs := appcontext.Get(ctx).Subs
 	for _, depID := range service.DependenciesIDs {
+		lastMsgID := subs.LastMessageID()
 		if err := m.startService(ctx, depID, env); err != nil {
 			return err
 		}
+		if err := m.waitTillReady(ctx, depID, lastMsgID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// Stop stops a running Service. If the service isn't running it returns ErrStatus.
+// Stop stops a running Service or cancels its pending restart.
+// If the service isn't running it returns ErrStatus.
 func (m *Manager) Stop(ctx context.Context, serviceID string) error {
 	return model.LockServiceE(ctx, serviceID, func(service *model.Service) error {
-		if service.Status != model.ServiceStatusRunning {
+		actual, ok := m.cancels.Load(serviceID)
+		switch service.Status {
+		case model.ServiceStatusRunning, model.ServiceStatusReady:
+		case model.ServiceStatusStarting:
+			// A Service waiting to be restarted has a cancel function.
+			if !ok {
+				return ErrStatus
+			}
+		default:
 			return ErrStatus
 		}
 		m.setStatus(ctx, service, model.ServiceStatusStopping)
 		service.MustStore(ctx)
-		actual, _ := m.cancels.Load(serviceID)
 		actual.(context.CancelFunc)()
 		return nil
 	})
@@ -98,32 +118,62 @@ func (m *Manager) startService(ctx context.Context, serviceID string, env []stri
 		switch service.Status {
 		case model.ServiceStatusStarting, model.ServiceStatusStopping:
 			return ErrStatus
-		case model.ServiceStatusRunning:
+		case model.ServiceStatusRunning, model.ServiceStatusReady:
 			return nil
 		}
+		service.RestartCount = 0
 		m.setStatus(ctx, service, model.ServiceStatusStarting)
 		service.MustStore(ctx)
-		fail := func() {
-			m.setStatus(ctx, service, model.ServiceStatusFailed)
+		return m.launch(ctx, service, env)
+	})
+}
+
+// restartService restarts a Service after a delay unless the context is canceled.
+func (m *Manager) restartService(ctx context.Context, serviceID string, env []string, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+	model.MustLockService(ctx, serviceID, func(service *model.Service) {
+		m.cancels.Delete(serviceID)
+		if ctx.Err() != nil {
+			m.setStatus(ctx, service, model.ServiceStatusStopped)
 			service.MustStore(ctx)
+			return
 		}
-		runner, close, err := m.createRunner(ctx, service, env)
-		if err != nil {
-			fail()
-			return err
-		}
-		if err := m.launchService(ctx, runner, service, env, close); err != nil {
-			fail()
-			close()
-		}
-		return err
+		m.launch(ctx, service, env)
 	})
 }
 
-func (m *Manager) createWriters(ctx context.Context, service *model.Service) (io.WriteCloser, io.WriteCloser, func()) {
+// launch launches a Service that is starting.
+// The Service must be locked.
+func (m *Manager) launch(ctx context.Context, service *model.Service, env []string) error {
+	fail := func() {
+		m.setStatus(ctx, service, model.ServiceStatusFailed)
+		service.MustStore(ctx)
+	}
+	matcher, err := newLogMatcher(service.Healthcheck)
+	if err != nil {
+		fail()
+		return err
+	}
+	runner, close, err := m.createRunner(ctx, service, env, matcher)
+	if err != nil {
+		fail()
+		return err
+	}
+	if err := m.launchService(ctx, runner, service, env, matcher, close); err != nil {
+		fail()
+		close()
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) createWriters(ctx context.Context, service *model.Service, matcher *logMatcher) (io.WriteCloser, io.WriteCloser, func()) {
 	log := appcontext.Get(ctx).Log
-	stdout := util.LineSplitter(ctx, log.InfoWithOwner, service.ID)
-	stderr := util.LineSplitter(ctx, log.WarningWithOwner, service.ID)
+	stdout := util.LineSplitter(ctx, matcher.wrap(log.InfoWithOwner), service.ID)
+	stderr := util.LineSplitter(ctx, matcher.wrap(log.WarningWithOwner), service.ID)
 	close := func() {
 		stdout.Close()
 		stderr.Close()
@@ -131,18 +181,16 @@ func (m *Manager) createWriters(ctx context.Context, service *model.Service) (io
 	return stdout, stderr, close
 }
 
-func (m *Manager) createRunner(ctx context.Context, service *model.Service, env []string) (appcontext.Runner, func(), error) {
+func (m *Manager) createRunner(ctx context.Context, service *model.Service, env []string, matcher *logMatcher) (appcontext.Runner, func(), error) {
 	appCtx := appcontext.Get(ctx)
-	stdout, stderr, close := m.createWriters(ctx, service)
-	dir := ""
+	stdout, stderr, close := m.createWriters(ctx, service, matcher)
 	if project := service.Project(ctx); project != nil {
 		if err := project.EnsureCloned(ctx); err != nil {
 			close()
 			return nil, nil, err
 		}
-		workspace := project.Workspace(ctx)
-		dir = appCtx.GetProjectPath(workspace.Slug, project.Slug)
 	}
+	dir := m.getDir(ctx, service)
 	env = append(os.Environ(), env...)
 	runner, err := appCtx.NewRunner(stdout, stderr, dir, env, appCtx.RunnerGracefulShutdownTimeout)
 	if err != nil {
@@ -152,7 +200,16 @@ func (m *Manager) createRunner(ctx context.Context, service *model.Service, env
 	return runner, close, nil
 }
 
-func (m *Manager) launchService(ctx context.Context, runner appcontext.Runner, service *model.Service, env []string, close func()) error {
+func (m *Manager) getDir(ctx context.Context, service *model.Service) string {
+	project := service.Project(ctx)
+	if project == nil {
+		return ""
+	}
+	workspace := project.Workspace(ctx)
+	return appcontext.Get(ctx).GetProjectPath(workspace.Slug, project.Slug)
+}
+
+func (m *Manager) launchService(ctx context.Context, runner appcontext.Runner, service *model.Service, env []string, matcher *logMatcher, close func()) error {
 	if err := m.runBeforeTasks(ctx, service, env); err != nil {
 		return err
 	}
@@ -163,16 +220,30 @@ func (m *Manager) launchService(ctx context.Context, runner appcontext.Runner, s
 	runCtx, cancel := m.createCtx(ctx)
 	m.cancels.Store(service.ID, cancel)
 	go m.runService(runCtx, runner, service, env, close)
+	if service.Healthcheck != nil {
+		go m.checkHealth(runCtx, service, env, matcher)
+	}
 	return nil
 }
 
 func (m *Manager) runService(ctx context.Context, runner appcontext.Runner, service *model.Service, env []string, close func()) {
 	appCtx := appcontext.Get(ctx)
 	appCtx.Log.InfoWithOwner(ctx, service.ID, service.Command)
+	started := time.Now()
 	err := runner.Run(ctx, service.Command)
 	close()
 	model.MustLockService(ctx, service.ID, func(service *model.Service) {
 		m.cancels.Delete(service.ID)
+		stopped := service.Status == model.ServiceStatusStopping
+		// The exit code only matters if the Service exited on its own.
+		service.LastExitCode = nil
+		if !stopped {
+			service.LastExitCode = shell.ExitCode(err)
+		}
+		// A run that outlasted the backoff isn't part of a crash loop, so MaxRetries starts over.
+		if time.Since(started) > restartDelay(service) {
+			service.RestartCount = 0
+		}
 		taskErr := m.runAfterTasks(ctx, service, env)
 		// Prioritize the command error over the task error.
 		if err != nil && taskErr != nil {
@@ -184,10 +255,53 @@ func (m *Manager) runService(ctx context.Context, runner appcontext.Runner, serv
 			appCtx.Log.ErrorWithOwner(ctx, appCtx.SystemID, "%s (%s)", err.Error(), service.LongString(ctx))
 			m.setStatus(ctx, service, model.ServiceStatusFailed)
 		}
+		if !stopped && m.shouldRestart(service, err) {
+			m.scheduleRestart(ctx, service, env)
+		}
 		service.MustStore(ctx)
 	})
 }
 
+// shouldRestart returns whether the RestartPolicy of a Service asks for a restart.
+func (m *Manager) shouldRestart(service *model.Service, err error) bool {
+	if service.MaxRetries > 0 && service.RestartCount >= service.MaxRetries {
+		return false
+	}
+	switch service.Restart {
+	case model.RestartPolicyAlways:
+		return true
+	case model.RestartPolicyOnFailure:
+		return err != nil
+	}
+	return false
+}
+
+// scheduleRestart sets a Service as starting and restarts it after an exponential backoff.
+// The Service must be locked.
+func (m *Manager) scheduleRestart(ctx context.Context, service *model.Service, env []string) {
+	delay := restartDelay(service)
+	service.RestartCount++
+	m.setStatus(ctx, service, model.ServiceStatusStarting)
+	appCtx := appcontext.Get(ctx)
+	appCtx.Log.InfoWithOwner(ctx, appCtx.SystemID, "%s  restarting in %s", service.LongString(ctx), delay)
+	waitCtx, cancel := m.createCtx(ctx)
+	m.cancels.Store(service.ID, cancel)
+	go m.restartService(waitCtx, service.ID, env, delay)
+}
+
+// restartDelay returns how long to wait before the next restart of a Service.
+// The backoff doubles after each consecutive restart, up to MaxRestartBackoff.
+func restartDelay(service *model.Service) time.Duration {
+	delay := service.BackoffDuration()
+	for i := 0; i < service.RestartCount && delay < MaxRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxRestartBackoff {
+		delay = MaxRestartBackoff
+	}
+	return delay
+}
+
 func (m *Manager) runBeforeTasks(ctx context.Context, service *model.Service, env []string) error {
 	return m.runTasks(ctx, service.BeforeIDs, env)
 }
@@ -226,6 +340,42 @@ func (m *Manager) waitTillDone(ctx context.Context, serviceID string, lastMsgID
 	<-subsCtx.Done()
 }
 
+// waitTillReady blocks until the Service is ready.
+// It returns ErrNotReady if the Service exits or doesn't pass its Healthcheck in time.
+func (m *Manager) waitTillReady(ctx context.Context, serviceID string, lastMsgID uint64) error {
+	service, err := model.LoadService(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	if service.Healthcheck == nil || service.IsReady() {
+		return nil
+	}
+	subsCtx, cancel := context.WithTimeout(ctx, service.Healthcheck.TimeoutDuration())
+	defer cancel()
+	var ready int32
+	subs := appcontext.Get(ctx).Subs
+	subs.Subscribe(subsCtx, model.MessageTypeServiceStored, lastMsgID, func(msg interface{}) {
+		service := msg.(*model.Service)
+		if service.ID != serviceID {
+			return
+		}
+		if service.IsReady() {
+			atomic.StoreInt32(&ready, 1)
+			cancel()
+			return
+		}
+		switch service.Status {
+		case model.ServiceStatusStopped, model.ServiceStatusFailed:
+			cancel()
+		}
+	})
+	<-subsCtx.Done()
+	if atomic.LoadInt32(&ready) == 0 {
+		return ErrNotReady
+	}
+	return nil
+}
+
 func (m *Manager) createCtx(ctx context.Context) (context.Context, context.CancelFunc) {
 	appCtx := appcontext.Get(ctx)
 	ctx = appcontext.With(context.Background(), appCtx)
@@ -265,6 +415,8 @@ func (m *Manager) addToCounter(status model.ServiceStatus, delta int64) {
 		atomic.AddInt64(&m.startingCounter, delta)
 	case model.ServiceStatusRunning:
 		atomic.AddInt64(&m.runningCounter, delta)
+	case model.ServiceStatusReady:
+		atomic.AddInt64(&m.readyCounter, delta)
 	case model.ServiceStatusStopping:
 		atomic.AddInt64(&m.stoppingCounter, delta)
 	case model.ServiceStatusFailed:
@@ -279,6 +431,7 @@ func (m *Manager) storeMetrics(ctx context.Context) {
 		metrics.Stopped = int(atomic.LoadInt64(&m.stoppedCounter))
 		metrics.Starting = int(atomic.LoadInt64(&m.startingCounter))
 		metrics.Running = int(atomic.LoadInt64(&m.runningCounter))
+		metrics.Ready = int(atomic.LoadInt64(&m.readyCounter))
 		metrics.Stopping = int(atomic.LoadInt64(&m.stoppingCounter))
 		metrics.Failed = int(atomic.LoadInt64(&m.failedCounter))
 		metrics.MustStore(ctx)
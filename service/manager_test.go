@@ -0,0 +1,148 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/interp"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/log"
+	"groundcontrol/model"
+	"groundcontrol/pubsub"
+	"groundcontrol/relay"
+	"groundcontrol/store"
+)
+
+// runnerFunc is a Runner that calls a function.
+type runnerFunc func(ctx context.Context, command string) error
+
+func (fn runnerFunc) Run(ctx context.Context, command string) error {
+	return fn(ctx, command)
+}
+
+// blockingRunner is a Runner whose commands run until they are canceled.
+var blockingRunner = runnerFunc(func(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+})
+
+// newTestContext creates an app context with a System and a Workspace.
+func newTestContext() context.Context {
+	systemID := relay.EncodeID(model.NodeTypeSystem)
+	ctx := appcontext.With(context.Background(), &appcontext.Context{
+		Nodes:    store.NewMemory(),
+		Log:      log.NewLogger(100, model.LogLevelError),
+		Subs:     pubsub.New(100),
+		SystemID: systemID,
+		NewRunner: func(io.Writer, io.Writer, string, []string, time.Duration) (appcontext.Runner, error) {
+			return blockingRunner, nil
+		},
+	})
+	system := &model.System{
+		ID:               systemID,
+		ServiceMetricsID: relay.EncodeID(model.NodeTypeServiceMetrics),
+		LogMetricsID:     relay.EncodeID(model.NodeTypeLogMetrics),
+	}
+	system.MustStore(ctx)
+	(&model.ServiceMetrics{ID: system.ServiceMetricsID}).MustStore(ctx)
+	(&model.LogMetrics{ID: system.LogMetricsID}).MustStore(ctx)
+	(&model.Workspace{ID: relay.EncodeID(model.NodeTypeWorkspace, "test"), Slug: "test"}).MustStore(ctx)
+	return ctx
+}
+
+// newTestService stores a running Service.
+func newTestService(ctx context.Context, service *model.Service) *model.Service {
+	service.ID = relay.EncodeID(model.NodeTypeService, "test", "service")
+	service.Name = "service"
+	service.Command = "serve"
+	service.WorkspaceID = relay.EncodeID(model.NodeTypeWorkspace, "test")
+	if service.Status == "" {
+		service.Status = model.ServiceStatusRunning
+	}
+	service.MustStore(ctx)
+	return service
+}
+
+func TestManager_runService(t *testing.T) {
+	errExit := interp.ExitStatus(3)
+	tests := []struct {
+		name             string
+		service          model.Service
+		duration         time.Duration
+		wantStatus       model.ServiceStatus
+		wantRestartCount int
+		wantExitCode     bool
+	}{{
+		"crash",
+		model.Service{Restart: model.RestartPolicyOnFailure, MaxRetries: 2, Backoff: 60000},
+		0,
+		model.ServiceStatusStarting,
+		1,
+		true,
+	}, {
+		"too many consecutive crashes",
+		model.Service{Restart: model.RestartPolicyOnFailure, MaxRetries: 2, Backoff: 60000, RestartCount: 2},
+		0,
+		model.ServiceStatusFailed,
+		2,
+		true,
+	}, {
+		"crash after running longer than the backoff",
+		model.Service{Restart: model.RestartPolicyOnFailure, MaxRetries: 2, Backoff: 1, RestartCount: 2},
+		20 * time.Millisecond,
+		model.ServiceStatusStarting,
+		1,
+		true,
+	}, {
+		"stopped",
+		model.Service{Restart: model.RestartPolicyAlways, Status: model.ServiceStatusStopping},
+		0,
+		model.ServiceStatusFailed,
+		0,
+		false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext()
+			m := NewManager()
+			service := newTestService(ctx, &tt.service)
+			runner := runnerFunc(func(context.Context, string) error {
+				time.Sleep(tt.duration)
+				return errExit
+			})
+
+			m.runService(ctx, runner, service, nil, func() {})
+
+			got := model.MustLoadService(ctx, service.ID)
+			if got.Status == model.ServiceStatusStarting {
+				// Cancel the pending restart.
+				assert.NoError(t, m.Stop(ctx, service.ID))
+			}
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantRestartCount, got.RestartCount)
+			if tt.wantExitCode {
+				assert.Equal(t, 3, *got.LastExitCode)
+			} else {
+				assert.Nil(t, got.LastExitCode)
+			}
+		})
+	}
+}
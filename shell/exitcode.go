@@ -0,0 +1,34 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shell
+
+import (
+	"mvdan.cc/sh/interp"
+)
+
+// ExitCode returns the exit code of a command given the error returned by a Runner.
+// It returns nil if the command didn't exit on its own.
+func ExitCode(err error) *int {
+	code := 0
+	if err == nil {
+		return &code
+	}
+	status, ok := err.(interp.ExitStatus)
+	if !ok {
+		return nil
+	}
+	code = int(status)
+	return &code
+}
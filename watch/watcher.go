@@ -0,0 +1,413 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"groundcontrol/appcontext"
+	"groundcontrol/job"
+	"groundcontrol/model"
+)
+
+// DefaultDebounce is the default duration without changes to wait for before syncing.
+const DefaultDebounce = 500 * time.Millisecond
+
+// reconcileKey is the debounce key used to update the watched directories.
+// It can't be confused with the path of a root since paths are never empty.
+const reconcileKey = ""
+
+// reconcileMessageTypes are the types of messages that can change which directories should be watched.
+var reconcileMessageTypes = []string{
+	model.MessageTypeDirectorySourceStored,
+	model.MessageTypeDirectorySourceDeleted,
+	model.MessageTypeGitSourceDeleted,
+	model.MessageTypeHTTPSourceDeleted,
+	model.MessageTypeWorkspaceStored,
+	model.MessageTypeWorkspaceDeleted,
+	model.MessageTypeProjectStored,
+	model.MessageTypeProjectDeleted,
+}
+
+// errLimit stops walking a directory once the maximum number of watched directories is reached.
+var errLimit = errors.New("too many watched directories")
+
+type rootKind int
+
+const (
+	rootSource rootKind = iota
+	rootProject
+)
+
+// root is a directory that is watched recursively on behalf of a Node.
+type root struct {
+	path    string
+	ownerID string
+	kind    rootKind
+}
+
+// Watcher watches the directories of Directory Sources and cloned Projects.
+// A Directory Source is synced when one of its YAML files changes, and a Project
+// checks for uncommitted changes when its working tree changes.
+type Watcher struct {
+	maxDirs  int
+	debounce time.Duration
+
+	fsw       *fsnotify.Watcher
+	roots     map[string]*root
+	dirs      map[string]*root
+	timers    map[string]*time.Timer
+	changeCh  chan struct{}
+	fireCh    chan string
+	hasWarned bool
+}
+
+// New creates a Watcher that watches at most maxDirs directories.
+// Changes are handled once nothing else changed for the debounce duration.
+func New(maxDirs int, debounce time.Duration) *Watcher {
+	return &Watcher{
+		maxDirs:  maxDirs,
+		debounce: debounce,
+		roots:    map[string]*root{},
+		dirs:     map[string]*root{},
+		timers:   map[string]*time.Timer{},
+		changeCh: make(chan struct{}, 1),
+		fireCh:   make(chan string),
+	}
+}
+
+// Watch starts watching and blocks until the context is done.
+// Since changes are also picked up by periodic syncs, it only logs a warning
+// and returns if the file watcher can't be created.
+func (w *Watcher) Watch(ctx context.Context) error {
+	appCtx := appcontext.Get(ctx)
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "could not watch files because %s", err.Error())
+		return nil
+	}
+	defer fsw.Close()
+	w.fsw = fsw
+
+	lastMsgID := appCtx.Subs.LastMessageID()
+	for _, messageType := range reconcileMessageTypes {
+		appCtx.Subs.Subscribe(ctx, messageType, lastMsgID, func(interface{}) {
+			select {
+			case w.changeCh <- struct{}{}:
+			default:
+			}
+		})
+	}
+	w.reconcile(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			for _, timer := range w.timers {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-w.changeCh:
+			w.schedule(ctx, reconcileKey)
+		case event := <-fsw.Events:
+			w.handleEvent(ctx, event)
+		case err := <-fsw.Errors:
+			appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "file watcher error: %s", err.Error())
+		case key := <-w.fireCh:
+			delete(w.timers, key)
+			w.fire(ctx, key)
+		}
+	}
+}
+
+// schedule calls fire with the key once nothing was scheduled with the same key for the debounce duration.
+func (w *Watcher) schedule(ctx context.Context, key string) {
+	if timer, ok := w.timers[key]; ok {
+		timer.Stop()
+	}
+	w.timers[key] = time.AfterFunc(w.debounce, func() {
+		select {
+		case w.fireCh <- key:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// fire handles changes once they have settled.
+func (w *Watcher) fire(ctx context.Context, key string) {
+	if key == reconcileKey {
+		w.reconcile(ctx)
+		return
+	}
+	r, ok := w.roots[key]
+	if !ok {
+		return
+	}
+	switch r.kind {
+	case rootSource:
+		w.syncSource(ctx, r)
+	case rootProject:
+		go w.syncProject(ctx, r)
+	}
+}
+
+// syncSource queues a Job to sync a Directory Source.
+// It tries again later if the Directory Source is already syncing since the Job could miss the changes.
+func (w *Watcher) syncSource(ctx context.Context, r *root) {
+	_, err := job.SyncDirectorySource(ctx, r.ownerID, false)
+	if err == job.ErrDuplicate {
+		w.schedule(ctx, r.path)
+		return
+	}
+	if err != nil && err != model.ErrNotFound {
+		appCtx := appcontext.Get(ctx)
+		appCtx.Log.WarningWithOwner(ctx, appCtx.SystemID, "could not sync source %s because %s", r.path, err.Error())
+	}
+}
+
+// syncProject checks if a Project has uncommitted changes.
+// Busy Projects are skipped since they check it themselves when they are done.
+// It is called in a Goroutine since the Project can stay locked for a while.
+func (w *Watcher) syncProject(ctx context.Context, r *root) {
+	err := model.LockProjectE(ctx, r.ownerID, func(project *model.Project) error {
+		if project.IsBusy() {
+			return nil
+		}
+		return project.SyncIsClean(ctx)
+	})
+	if err != nil && err != model.ErrNotFound {
+		appCtx := appcontext.Get(ctx)
+		appCtx.Log.WarningWithOwner(ctx, r.ownerID, "could not check for uncommitted changes because %s", err.Error())
+	}
+}
+
+// handleEvent watches directories that were created, forgets those that were removed, and schedules a sync if
+// the change is relevant.
+func (w *Watcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	r, ok := w.dirs[filepath.Dir(event.Name)]
+	if !ok {
+		return
+	}
+	_, isDir := w.dirs[event.Name]
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.removeDir(event.Name)
+	}
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			isDir = true
+			if info.Name() != ".git" {
+				w.addDirs(ctx, r, event.Name)
+			}
+		}
+	}
+	if isRelevant(r, event.Name, isDir) {
+		w.schedule(ctx, r.path)
+	}
+}
+
+// isRelevant returns whether a change to a file or directory within a root requires a sync.
+func isRelevant(r *root, name string, isDir bool) bool {
+	switch r.kind {
+	case rootSource:
+		return isDir || filepath.Ext(name) == ".yml"
+	case rootProject:
+		if filepath.Dir(name) != filepath.Join(r.path, ".git") {
+			return true
+		}
+		// Checking for uncommitted changes rewrites the index so it must be ignored to avoid a loop.
+		base := filepath.Base(name)
+		return base != "index" && !strings.HasSuffix(base, ".lock")
+	}
+	return false
+}
+
+// reconcile updates the watched directories to match the Directory Sources and cloned Projects.
+func (w *Watcher) reconcile(ctx context.Context) {
+	desired := desiredRoots(ctx)
+	for path, r := range w.roots {
+		if d, ok := desired[path]; !ok || *d != *r {
+			w.removeRoot(r)
+		}
+	}
+	var paths []string
+	for path := range desired {
+		if _, ok := w.roots[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	// Directory Sources are watched first since they are small and tell which Projects exist.
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := desired[paths[i]], desired[paths[j]]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.path < b.path
+	})
+	for _, path := range paths {
+		r := desired[path]
+		w.roots[path] = r
+		w.addDirs(ctx, r, path)
+	}
+}
+
+// desiredRoots returns the roots that should be watched, keyed by path.
+func desiredRoots(ctx context.Context) map[string]*root {
+	roots := map[string]*root{}
+	appCtx := appcontext.Get(ctx)
+	viewer, err := model.LoadUser(ctx, appCtx.ViewerID)
+	if err != nil {
+		return roots
+	}
+	for _, sourceID := range viewer.SourcesIDs {
+		source, err := model.LoadSource(ctx, sourceID)
+		if err != nil {
+			continue
+		}
+		if directorySource, ok := source.(*model.DirectorySource); ok {
+			roots[directorySource.Directory] = &root{
+				path:    directorySource.Directory,
+				ownerID: directorySource.ID,
+				kind:    rootSource,
+			}
+		}
+		for _, workspaceID := range source.GetWorkspacesIDs() {
+			workspace, err := model.LoadWorkspace(ctx, workspaceID)
+			if err != nil {
+				continue
+			}
+			for _, projectID := range workspace.ProjectsIDs {
+				project, err := model.LoadProject(ctx, projectID)
+				if err != nil || !project.IsCloned(ctx) {
+					continue
+				}
+				path := project.Path(ctx)
+				roots[path] = &root{path: path, ownerID: project.ID, kind: rootProject}
+			}
+		}
+	}
+	return roots
+}
+
+// removeRoot stops watching all the directories of a root.
+func (w *Watcher) removeRoot(r *root) {
+	for path, dirRoot := range w.dirs {
+		if dirRoot == r {
+			_ = w.fsw.Remove(path)
+			delete(w.dirs, path)
+		}
+	}
+	if timer, ok := w.timers[r.path]; ok {
+		timer.Stop()
+		delete(w.timers, r.path)
+	}
+	delete(w.roots, r.path)
+}
+
+// removeDir stops watching a directory and its subdirectories.
+func (w *Watcher) removeDir(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			// The directory is likely gone already, in which case it isn't watched anymore.
+			_ = w.fsw.Remove(path)
+			delete(w.dirs, path)
+		}
+	}
+}
+
+// addDirs watches a directory of a root and its subdirectories.
+// Git directories aren't watched, except the top of the one of a Project to notice commits.
+// Directories ignored by Git are skipped since they often contain a lot of generated files.
+func (w *Watcher) addDirs(ctx context.Context, r *root, dir string) {
+	var ignored map[string]bool
+	if r.kind == rootProject {
+		ignored = ignoredDirs(r.path, dir)
+	}
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if info.Name() == ".git" {
+			if r.kind == rootProject && filepath.Dir(path) == r.path && !w.addDir(ctx, r, path) {
+				return errLimit
+			}
+			return filepath.SkipDir
+		}
+		if ignored[path] {
+			return filepath.SkipDir
+		}
+		if !w.addDir(ctx, r, path) {
+			return errLimit
+		}
+		return nil
+	})
+}
+
+// addDir watches a directory of a root.
+// It returns false if the maximum number of watched directories is reached.
+func (w *Watcher) addDir(ctx context.Context, r *root, dir string) bool {
+	if _, ok := w.dirs[dir]; ok {
+		return true
+	}
+	appCtx := appcontext.Get(ctx)
+	if len(w.dirs) >= w.maxDirs {
+		if !w.hasWarned {
+			appCtx.Log.WarningWithOwner(
+				ctx,
+				appCtx.SystemID,
+				"file watcher reached the maximum of %d directories, some changes will only be noticed by periodic jobs",
+				w.maxDirs,
+			)
+			w.hasWarned = true
+		}
+		return false
+	}
+	if err := w.fsw.Add(dir); err != nil {
+		appCtx.Log.DebugWithOwner(ctx, appCtx.SystemID, "could not watch %s because %s", dir, err.Error())
+		return true
+	}
+	w.dirs[dir] = r
+	return true
+}
+
+// ignoredDirs returns the directories within a directory of a repository that are ignored by Git.
+func ignoredDirs(repo, dir string) map[string]bool {
+	cmd := exec.Command("git", "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "-z", "--", dir)
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	ignored := map[string]bool{}
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		if bytes.HasSuffix(entry, []byte("/")) {
+			path := filepath.Join(repo, filepath.FromSlash(string(entry)))
+			ignored[path] = true
+		}
+	}
+	return ignored
+}
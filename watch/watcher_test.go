@@ -0,0 +1,71 @@
+// Copyright 2019 Stratumn
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRelevant(t *testing.T) {
+	source := &root{path: filepath.FromSlash("/sources/mine"), kind: rootSource}
+	project := &root{path: filepath.FromSlash("/workspaces/app/api"), kind: rootProject}
+	type args struct {
+		r     *root
+		name  string
+		isDir bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{{
+		"source YAML file",
+		args{source, "/sources/mine/workspaces.yml", false},
+		true,
+	}, {
+		"source other file",
+		args{source, "/sources/mine/README.md", false},
+		false,
+	}, {
+		"source directory",
+		args{source, "/sources/mine/team", true},
+		true,
+	}, {
+		"project file",
+		args{project, "/workspaces/app/api/main.go", false},
+		true,
+	}, {
+		"project Git file",
+		args{project, "/workspaces/app/api/.git/COMMIT_EDITMSG", false},
+		true,
+	}, {
+		"project Git index",
+		args{project, "/workspaces/app/api/.git/index", false},
+		false,
+	}, {
+		"project Git lock",
+		args{project, "/workspaces/app/api/.git/HEAD.lock", false},
+		false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isRelevant(tt.args.r, filepath.FromSlash(tt.args.name), tt.args.isDir)
+			assert.Equal(t, tt.want, got, "isRelevant()")
+		})
+	}
+}
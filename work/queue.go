@@ -24,6 +24,7 @@ import (
 	"groundcontrol/appcontext"
 	"groundcontrol/model"
 	"groundcontrol/relay"
+	"groundcontrol/retry"
 )
 
 // Worker is a function that is executed once a job is running.
@@ -32,6 +33,7 @@ type Worker = func(ctx context.Context) error
 // Queue queues Jobs and executes them.
 type Queue struct {
 	concurrency int
+	timeouts    map[string]time.Duration
 
 	hiCh    chan message
 	ch      chan message
@@ -50,16 +52,19 @@ type Queue struct {
 }
 
 type message struct {
-	Job *model.Job
-	Fn  Worker
+	Job   *model.Job
+	Retry *retry.Policy
+	Fn    Worker
 }
 
 // NewQueue creates a Queue with given concurrency.
 // ChannelSize is the size for each channel of the queue.
 // A job will fail if the channel of the corresponding priority is full.
-func NewQueue(concurrency, channelSize int) *Queue {
+// Timeouts maps job names to the maximum duration of an attempt, jobs without one never time out.
+func NewQueue(concurrency, channelSize int, timeouts map[string]time.Duration) *Queue {
 	return &Queue{
 		concurrency: concurrency,
+		timeouts:    timeouts,
 		lastID:      uint64(time.Now().Unix()),
 		ch:          make(chan message, channelSize),
 		hiCh:        make(chan message, channelSize),
@@ -99,20 +104,21 @@ func (q *Queue) Work(ctx context.Context) error {
 }
 
 // Add adds a job to the queue and returns the job's ID.
-func (q *Queue) Add(ctx context.Context, name, ownerID string, highPriority bool, fn Worker) string {
+// If the retry policy isn't nil, the job is retried when it fails.
+func (q *Queue) Add(ctx context.Context, name, ownerID string, highPriority bool, policy *retry.Policy, fn Worker) string {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	// Don't save job til we know if it's queued or starts running immediately.
-	job := q.initJob(ctx, name, ownerID, highPriority)
+	job := q.initJob(ctx, name, ownerID, highPriority, policy)
 	if q.done {
-		q.setStatus(ctx, job, model.JobStatusFailed)
+		q.setFailed(ctx, job, model.JobFailureReasonQueueDone)
 		job.MustStore(ctx)
 		q.addJobToSystem(ctx, job.ID)
 		appCtx := appcontext.Get(ctx)
 		appCtx.Log.InfoWithOwner(ctx, appCtx.SystemID, "%s  job failed because queue is done", job.LongString(ctx))
 		return job.ID
 	}
-	msg := message{Job: job, Fn: fn}
+	msg := message{Job: job, Retry: policy, Fn: fn}
 	q.send(ctx, msg, highPriority)
 	return job.ID
 }
@@ -125,7 +131,8 @@ func (q *Queue) Stop(ctx context.Context, id string) error {
 	return model.LockJobE(ctx, id, func(job *model.Job) error {
 		switch job.Status {
 		case model.JobStatusQueued:
-			q.setStatus(ctx, job, model.JobStatusFailed)
+			job.NextRetryAt = nil
+			q.setFailed(ctx, job, model.JobFailureReasonStopped)
 			log.ErrorWithOwner(ctx, systemID, "%s  job failed because it was stopped", job.LongString(ctx))
 		case model.JobStatusRunning:
 			q.setStatus(ctx, job, model.JobStatusStopping)
@@ -165,7 +172,7 @@ func (q *Queue) send(ctx context.Context, msg message, highPriority bool) {
 		})
 	default:
 		model.MustLockJob(ctx, msg.Job.ID, func(job *model.Job) {
-			q.setStatus(ctx, job, model.JobStatusFailed)
+			q.setFailed(ctx, job, model.JobFailureReasonQueueFull)
 			job.MustStore(ctx)
 			q.addJobToSystem(ctx, job.ID)
 			log.InfoWithOwner(ctx, systemID, "%s  job failed because queue is full", job.LongString(ctx))
@@ -191,7 +198,7 @@ func (q *Queue) run(ctx context.Context, msg message) {
 		job.UpdatedAt = model.DateTime(time.Now())
 		q.setStatus(ctx, job, model.JobStatusRunning)
 		job.MustStore(ctx)
-		jobCtx, cancel = q.createCtx(ctx)
+		jobCtx, cancel = q.createCtx(ctx, job)
 		q.cancels.Store(job.ID, cancel)
 	})
 	if stopped {
@@ -199,21 +206,54 @@ func (q *Queue) run(ctx context.Context, msg message) {
 	}
 	appCtx := appcontext.Get(ctx)
 	appCtx.Log.InfoWithOwner(ctx, appCtx.SystemID, "%s  job running", msg.Job.LongString(ctx))
-	q.handleJobErr(ctx, jobID, msg.Fn(jobCtx))
+	err := msg.Fn(jobCtx)
+	timedOut := jobCtx.Err() == context.DeadlineExceeded
 	cancel()
+	q.handleJobErr(ctx, msg, err, timedOut)
 }
 
-func (q *Queue) handleJobErr(ctx context.Context, jobID string, err error) {
+func (q *Queue) handleJobErr(ctx context.Context, msg message, err error, timedOut bool) {
 	appCtx := appcontext.Get(ctx)
 	log := appCtx.Log
 	systemID := appCtx.SystemID
-	model.MustLockJob(ctx, jobID, func(job *model.Job) {
-		if err != nil {
-			q.setStatus(ctx, job, model.JobStatusFailed)
-			log.ErrorWithOwner(ctx, systemID, "%s  job failed because %s", job.LongString(ctx), err.Error())
-		} else {
+	model.MustLockJob(ctx, msg.Job.ID, func(job *model.Job) {
+		switch {
+		case err == nil:
 			q.setStatus(ctx, job, model.JobStatusDone)
 			log.InfoWithOwner(ctx, systemID, "%s  job done", job.LongString(ctx))
+		case timedOut:
+			// A Job that timed out is likely to hang again, so it isn't retried.
+			errMsg := fmt.Sprintf("it timed out after %s", time.Duration(*job.Timeout)*time.Millisecond)
+			job.Error = &errMsg
+			q.setFailed(ctx, job, model.JobFailureReasonTimeout)
+			log.ErrorWithOwner(ctx, systemID, "%s  job failed because %s", job.LongString(ctx), errMsg)
+		case job.Status != model.JobStatusStopping && msg.Retry.ShouldRetry(job.Attempt, err):
+			// A Job that was stopped is never retried.
+			errMsg := err.Error()
+			job.Error = &errMsg
+			delay := msg.Retry.Delay(job.Attempt)
+			nextRetryAt := model.DateTime(time.Now().Add(delay))
+			job.NextRetryAt = &nextRetryAt
+			q.setStatus(ctx, job, model.JobStatusQueued)
+			log.WarningWithOwner(
+				ctx,
+				systemID,
+				"%s  job attempt %d failed because %s, retrying in %s",
+				job.LongString(ctx),
+				job.Attempt,
+				errMsg,
+				delay,
+			)
+			go q.retry(ctx, msg, delay)
+		default:
+			reason := model.JobFailureReasonError
+			if job.Status == model.JobStatusStopping {
+				reason = model.JobFailureReasonStopped
+			}
+			errMsg := err.Error()
+			job.Error = &errMsg
+			q.setFailed(ctx, job, reason)
+			log.ErrorWithOwner(ctx, systemID, "%s  job failed because %s", job.LongString(ctx), errMsg)
 		}
 		q.cancels.Delete(job.ID)
 		job.UpdatedAt = model.DateTime(time.Now())
@@ -221,6 +261,37 @@ func (q *Queue) handleJobErr(ctx context.Context, jobID string, err error) {
 	})
 }
 
+// retry sends a Job back to the queue after a delay, unless it was stopped in the meantime.
+func (q *Queue) retry(ctx context.Context, msg message, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+	appCtx := appcontext.Get(ctx)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := false
+	model.MustLockJob(ctx, msg.Job.ID, func(job *model.Job) {
+		if job.Status != model.JobStatusQueued {
+			return
+		}
+		job.NextRetryAt = nil
+		job.UpdatedAt = model.DateTime(time.Now())
+		if q.done || ctx.Err() != nil {
+			q.setFailed(ctx, job, model.JobFailureReasonQueueDone)
+			job.MustStore(ctx)
+			appCtx.Log.InfoWithOwner(ctx, appCtx.SystemID, "%s  job failed because queue is done", job.LongString(ctx))
+			return
+		}
+		job.Attempt++
+		job.MustStore(ctx)
+		queued = true
+	})
+	if queued {
+		q.send(ctx, msg, msg.Job.Priority == model.JobPriorityHigh)
+	}
+}
+
 func (q *Queue) clean(ctx context.Context) {
 	q.mu.Lock()
 	q.done = true
@@ -238,7 +309,7 @@ func (q *Queue) drain(ctx context.Context, ch chan message) {
 					*job = *msg.Job
 				}
 				job.UpdatedAt = model.DateTime(time.Now())
-				q.setStatus(ctx, job, model.JobStatusFailed)
+				q.setFailed(ctx, job, model.JobFailureReasonQueueDone)
 				job.MustStore(ctx)
 			})
 		default:
@@ -248,21 +319,33 @@ func (q *Queue) drain(ctx context.Context, ch chan message) {
 	}
 }
 
-func (q *Queue) initJob(ctx context.Context, name, ownerID string, highPriority bool) *model.Job {
+func (q *Queue) initJob(ctx context.Context, name, ownerID string, highPriority bool, policy *retry.Policy) *model.Job {
 	priority := model.JobPriorityNormal
 	if highPriority {
 		priority = model.JobPriorityHigh
 	}
+	var timeout *int
+	if duration := q.timeouts[name]; duration > 0 {
+		ms := int(duration / time.Millisecond)
+		timeout = &ms
+	}
+	maxAttempts := 1
+	if policy != nil && policy.MaxAttempts > 1 {
+		maxAttempts = policy.MaxAttempts
+	}
 	id := atomic.AddUint64(&q.lastID, 1)
 	jobID := relay.EncodeID(model.NodeTypeJob, fmt.Sprint(id))
 	now := model.DateTime(time.Now())
 	return &model.Job{
-		ID:        jobID,
-		Priority:  priority,
-		Name:      name,
-		CreatedAt: now,
-		UpdatedAt: now,
-		OwnerID:   ownerID,
+		ID:          jobID,
+		Priority:    priority,
+		Name:        name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		OwnerID:     ownerID,
+		Attempt:     1,
+		MaxAttempts: maxAttempts,
+		Timeout:     timeout,
 	}
 }
 
@@ -274,12 +357,23 @@ func (q *Queue) addJobToSystem(ctx context.Context, jobID string) {
 	})
 }
 
-func (q *Queue) createCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+// createCtx creates the context of an attempt of a Job.
+// It has a deadline if the Job has a timeout.
+func (q *Queue) createCtx(ctx context.Context, job *model.Job) (context.Context, context.CancelFunc) {
 	appCtx := appcontext.Get(ctx)
 	ctx = appcontext.With(context.Background(), appCtx)
+	ctx = appcontext.WithJobID(ctx, job.ID)
+	if job.Timeout != nil {
+		return context.WithTimeout(ctx, time.Duration(*job.Timeout)*time.Millisecond)
+	}
 	return context.WithCancel(ctx)
 }
 
+func (q *Queue) setFailed(ctx context.Context, job *model.Job, reason model.JobFailureReason) {
+	job.FailureReason = &reason
+	q.setStatus(ctx, job, model.JobStatusFailed)
+}
+
 func (q *Queue) setStatus(ctx context.Context, job *model.Job, status model.JobStatus) {
 	was := job.Status
 	if was == status {